@@ -0,0 +1,84 @@
+package nblog
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Banner makes the handler log a record describing the program as soon as it's created, so that each log says what
+// wrote it. The record has the message "program started" and these attributes:
+//
+//   - program: the base name of the executable
+//   - args: the command-line arguments, not including the program name
+//   - host: the host name
+//   - pid: the process ID
+//   - go: the Go version the program was built with
+//   - version: the main module's version, from its build information
+//   - modules: the versions of the modules the program depends on, keyed by module path
+//   - level: the handler's level
+//   - timestamp_format: the layout from [TimestampFormat]
+//
+// The banner is written regardless of the handler's level. [Close] writes the matching record, or call [Shutdown] to
+// write it without closing the handler.
+func Banner(show bool) Option {
+	return func(h slog.Handler) {
+		base(h).banner = show
+	}
+}
+
+// writeBanner logs the startup record, once all the options are known.
+func (h *baseHandler) writeBanner() {
+	if !h.banner {
+		return
+	}
+	hostname, _ := os.Hostname()
+	attrs := []slog.Attr{
+		slog.String("program", filepath.Base(os.Args[0])),
+		slog.Any("args", os.Args[1:]),
+		slog.String("host", hostname),
+		slog.Int("pid", os.Getpid()),
+		slog.String("go", runtime.Version()),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		attrs = append(attrs, slog.String("version", info.Main.Version), moduleVersions(info))
+	}
+	attrs = append(attrs,
+		slog.String("level", h.minimumLevel().String()),
+		slog.String("timestamp_format", h.timestampFormat),
+	)
+	h.note("program started", attrs...)
+}
+
+func moduleVersions(info *debug.BuildInfo) slog.Attr {
+	modules := make([]slog.Attr, 0, len(info.Deps))
+	for _, dep := range info.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version += " => " + dep.Replace.Path + " " + dep.Replace.Version
+		}
+		modules = append(modules, slog.String(dep.Path, version))
+	}
+	return slog.Attr{Key: "modules", Value: slog.GroupValue(modules...)}
+}
+
+// Shutdown logs a record saying that the program is stopping, with the time since h was created as "uptime" and the
+// given reason as "reason". Like the record from [Banner], it's written regardless of the handler's level. The handler
+// must have been created by [New] or derived from such a handler.
+func Shutdown(h slog.Handler, reason string) {
+	b, ok := rootHandler(h)
+	if !ok {
+		panic("Shutdown applied to wrong type")
+	}
+	b.shutdown(reason)
+}
+
+func (h *baseHandler) shutdown(reason string) {
+	h.note("program stopping",
+		slog.Duration("uptime", time.Since(h.started).Round(time.Millisecond)),
+		slog.String("reason", reason),
+	)
+}
@@ -0,0 +1,44 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestBanner(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	h := nblog.New(output, nblog.Banner(true), nblog.Level(slog.LevelError), nblog.TimestampFormat(nblog.TimeOnlyFormat))
+	nblog.Shutdown(slog.New(h).With("a", 1).Handler(), "signal: terminated")
+
+	g.Expect(output.Lines).To(HaveLen(2))
+	banner, err := nblog.ParseLine(output.Lines[0])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(banner.Message).To(Equal("program started"))
+	g.Expect(banner.Level).To(Equal(slog.LevelInfo))
+	for path, expected := range map[string]any{
+		"program":          "nblog.test",
+		"pid":              int64(os.Getpid()),
+		"level":            "ERROR",
+		"timestamp_format": nblog.TimeOnlyFormat,
+	} {
+		value, ok := banner.Lookup(path)
+		g.Expect(ok).To(BeTrue(), path)
+		g.Expect(value.Any()).To(Equal(expected), path)
+	}
+	for _, path := range []string{"args", "host", "go", "version", "modules"} {
+		_, ok := banner.Lookup(path)
+		g.Expect(ok).To(BeTrue(), path)
+	}
+
+	g.Expect(output.Lines[1]).To(MatchRegexp(
+		`<INFO> program stopping \{"uptime": "\d+(\.\d+)?m?s", "reason": "signal: terminated"\}$`,
+	))
+}
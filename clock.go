@@ -0,0 +1,84 @@
+package nblog
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// zoneSuffix is the layout appended to the timestamp format by [ShowTimeZone].
+const zoneSuffix = "Z07:00"
+
+// processStart approximates the time the process started, for [ElapsedSinceStart].
+var processStart = time.Now()
+
+// Elapsed selects whether a handler writes elapsed times in place of timestamps.
+type Elapsed int
+
+const (
+	// ElapsedOff writes ordinary timestamps. This is the default.
+	ElapsedOff Elapsed = iota
+	// ElapsedSinceStart writes the time since the process started, in seconds, as in "+1.250331".
+	ElapsedSinceStart
+	// ElapsedSincePrevious writes the time since the previous record from the same handler or any handler derived from
+	// it, in seconds. The first record is measured from the start of the process.
+	ElapsedSincePrevious
+)
+
+// TimeLocation makes the handler convert timestamps to the given location, such as [time.UTC], before formatting them.
+// By default, timestamps are formatted in whatever location they have, which for records from [slog.Logger] is
+// [time.Local].
+func TimeLocation(loc *time.Location) Option {
+	return func(h slog.Handler) {
+		base(h).location = loc
+	}
+}
+
+// ShowTimeZone adds the time zone offset to each timestamp, as in "2024-10-18 13:45:00.000-05:00", or with a "Z"
+// suffix for UTC. [LineParser] recognizes timestamps with this suffix in both of the standard formats.
+func ShowTimeZone(show bool) Option {
+	return func(h slog.Handler) {
+		base(h).showTimeZone = show
+	}
+}
+
+// ElapsedTime makes the handler write elapsed times instead of timestamps, which helps when profiling a sequence of
+// operations, such as a program's startup. Elapsed times can't be parsed back into timestamps, so logs written this way
+// can't be merged by time with [Merger].
+func ElapsedTime(mode Elapsed) Option {
+	return func(h slog.Handler) {
+		base(h).elapsed = mode
+	}
+}
+
+// formatTime formats a timestamp according to the handler's time options.
+func (h *baseHandler) formatTime(t time.Time) string {
+	switch h.elapsed {
+	case ElapsedSinceStart:
+		return formatElapsed(t.Sub(processStart))
+	case ElapsedSincePrevious:
+		previous := h.previousTime.Swap(t.UnixNano())
+		if previous == 0 {
+			return formatElapsed(t.Sub(processStart))
+		}
+		return formatElapsed(t.Sub(time.Unix(0, previous)))
+	}
+	if h.location != nil {
+		t = t.In(h.location)
+	}
+	layout := h.timestampFormat
+	if h.showTimeZone {
+		layout += zoneSuffix
+	}
+	return t.Format(layout)
+}
+
+// formatElapsed writes a duration as a signed number of seconds with microsecond precision.
+func formatElapsed(d time.Duration) string {
+	const precision = 6
+	s := strconv.FormatFloat(d.Seconds(), 'f', precision, 64)
+	if d >= 0 {
+		s = "+" + s
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestTimeLocationAndZone(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	h := nblog.New(output, nblog.TimeLocation(time.FixedZone("EST", -5*60*60)), nblog.ShowTimeZone(true))
+	when := time.Date(2024, time.October, 18, 13, 45, 0, 0, time.UTC)
+	g.Expect(h.Handle(t.Context(), slog.NewRecord(when, slog.LevelInfo, "message", 0))).To(Succeed())
+
+	utc := nblog.New(output, nblog.TimeLocation(time.UTC), nblog.ShowTimeZone(true),
+		nblog.TimestampFormat(nblog.TimeOnlyFormat))
+	g.Expect(utc.Handle(t.Context(), slog.NewRecord(when, slog.LevelInfo, "message", 0))).To(Succeed())
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		HavePrefix("2024-10-18 08:45:00.000-05:00 ["),
+		HavePrefix("13:45:00.000Z ["),
+	))
+	entry, err := nblog.ParseLine(output.Lines[0])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entry.Time.Equal(when)).To(BeTrue())
+}
+
+func TestElapsedTime(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	h := nblog.New(output, nblog.ElapsedTime(nblog.ElapsedSincePrevious))
+	start := time.Now()
+	for _, offset := range []time.Duration{0, 1500 * time.Millisecond, 1750 * time.Millisecond} {
+		r := slog.NewRecord(start.Add(offset), slog.LevelInfo, "step", 0)
+		g.Expect(slog.New(h).WithGroup("g").Handler().Handle(t.Context(), r)).To(Succeed())
+	}
+
+	sinceStart := &LineBuffer{}
+	slog.New(nblog.New(sinceStart, nblog.ElapsedTime(nblog.ElapsedSinceStart))).Info("step")
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		MatchRegexp(`^\+\d+\.\d{6} \[\d+\] <INFO> step$`),
+		HavePrefix("+1.500000 ["),
+		HavePrefix("+0.250000 ["),
+	))
+	g.Expect(sinceStart.Lines).To(HaveExactElements(MatchRegexp(`^\+\d+\.\d{6} \[\d+\] <INFO> TestElapsedTime: step$`)))
+}
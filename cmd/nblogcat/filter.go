@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sweetkennedy.net/nblog"
+)
+
+// predicate decides whether an entry should be printed.
+type predicate func(*nblog.Entry) bool
+
+// filter is the conjunction of all the predicates requested on the command line.
+type filter []predicate
+
+func (f filter) match(e *nblog.Entry) bool {
+	for _, p := range f {
+		if !p(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func minLevel(level slog.Level) predicate {
+	return func(e *nblog.Entry) bool {
+		return e.Level >= level
+	}
+}
+
+func since(t time.Time) predicate {
+	return func(e *nblog.Entry) bool {
+		return !e.Time.IsZero() && !e.Time.Before(t)
+	}
+}
+
+func until(t time.Time) predicate {
+	return func(e *nblog.Entry) bool {
+		return !e.Time.IsZero() && e.Time.Before(t)
+	}
+}
+
+func pids(list []string) predicate {
+	return func(e *nblog.Entry) bool {
+		return slices.Contains(list, e.Pid)
+	}
+}
+
+func caller(re *regexp.Regexp) predicate {
+	return func(e *nblog.Entry) bool {
+		return re.MatchString(e.Caller)
+	}
+}
+
+// timeLayouts are the formats accepted for the -since and -until flags.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	nblog.FullDateFormat,
+	time.DateTime,
+	time.DateOnly,
+	nblog.TimeOnlyFormat,
+	time.TimeOnly,
+}
+
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+// operators lists the comparison operators allowed in attribute predicates. Two-character operators come first so
+// that "a>=1" isn't read as "a" > "=1".
+var operators = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// parseAttrPredicate parses expressions like "jobid=42" or "catalog.size>1000". The left side is a dotted attribute
+// path. Values compare numerically when both sides are numbers and as strings otherwise. The "~" operator matches a
+// regular expression.
+func parseAttrPredicate(expr string) (predicate, error) {
+	idx := strings.IndexAny(expr, "!=<>~")
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid attribute predicate %q", expr)
+	}
+	path, rest := expr[:idx], expr[idx:]
+	for _, op := range operators {
+		if operand, ok := strings.CutPrefix(rest, op); ok {
+			return attrPredicate(path, op, operand)
+		}
+	}
+	return nil, fmt.Errorf("invalid operator in attribute predicate %q", expr)
+}
+
+func attrPredicate(path, op, operand string) (predicate, error) {
+	if op == "~" {
+		re, err := regexp.Compile(operand)
+		if err != nil {
+			return nil, err
+		}
+		return func(e *nblog.Entry) bool {
+			v, ok := e.Lookup(path)
+			return ok && re.MatchString(v.String())
+		}, nil
+	}
+	return func(e *nblog.Entry) bool {
+		v, ok := e.Lookup(path)
+		return ok && compareResult(op, compare(v, operand))
+	}, nil
+}
+
+// compare returns a negative number, zero, or a positive number as v is less than, equal to, or greater than operand.
+func compare(v slog.Value, operand string) int {
+	if n, ok := numeric(v); ok {
+		if m, err := strconv.ParseFloat(operand, 64); err == nil {
+			switch {
+			case n < m:
+				return -1
+			case n > m:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(v.String(), operand)
+}
+
+func numeric(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	case slog.KindFloat64:
+		return v.Float64(), true
+	default:
+		return 0, false
+	}
+}
+
+func compareResult(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return false
+	}
+}
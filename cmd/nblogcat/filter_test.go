@@ -0,0 +1,50 @@
+package main
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestAttrPredicate(t *testing.T) {
+	t.Parallel()
+
+	entry := nblog.Entry{Attrs: []slog.Attr{
+		slog.Int("jobid", 42),
+		slog.Group("catalog", slog.Int("size", 1500), slog.String("name", "client_1")),
+	}}
+	cases := map[string]bool{
+		"jobid=42":              true,
+		"jobid!=42":             false,
+		"jobid>=42":             true,
+		"jobid<42":              false,
+		"catalog.size>1000":     true,
+		"catalog.size<=1000":    false,
+		"catalog.name=client_1": true,
+		"catalog.name~^client":  true,
+		"catalog.missing=1":     false,
+	}
+	for expr, expected := range cases {
+		t.Run(expr, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			p, err := parseAttrPredicate(expr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(p(&entry)).To(Equal(expected))
+		})
+	}
+}
+
+func TestInvalidAttrPredicate(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	for _, expr := range []string{"jobid", "=42", "a~("} {
+		_, err := parseAttrPredicate(expr)
+		g.Expect(err).To(HaveOccurred(), expr)
+	}
+}
@@ -0,0 +1,244 @@
+// Command nblogcat filters and converts log files written by the nblog handler.
+//
+// Usage:
+//
+//	nblogcat [flags] [file ...]
+//
+// With no files, or when a file is "-", nblogcat reads standard input. Each line is parsed as a NetBackup legacy log
+// line and printed if it passes every filter. Lines that can't be parsed are treated as continuations of the previous
+// record; in raw mode they're printed along with it, and in the other modes they're dropped.
+//
+// The flags are:
+//
+//	-level LEVEL
+//		Print only records at or above LEVEL, such as "WARN" or "DEBUG-2".
+//	-since TIME, -until TIME
+//		Print only records logged at or after, or strictly before, TIME.
+//	-pid PID
+//		Print only records from the given process. Repeat to allow several processes.
+//	-caller REGEXP
+//		Print only records whose caller matches REGEXP.
+//	-where PREDICATE
+//		Print only records whose attributes satisfy PREDICATE, such as "jobid=42" or "catalog.size>1000". The
+//		operators are =, !=, <, <=, >, >=, and ~ (regular-expression match). Repeat to require several predicates.
+//	-format FORMAT
+//		Output format: raw (the default, lines unchanged), jsonl, csv, or text (slog.TextHandler format). Except in
+//		raw output, the attributes of each line are kept apart from the fields that describe the line: jsonl and
+//		text output put them in an "attrs" group, and csv output in an "attrs" column.
+//	-layout LAYOUT
+//		Timestamp layout used by the log files, à la time.Time.Format. Repeat to try several layouts. The default
+//		accepts both nblog.FullDateFormat and nblog.TimeOnlyFormat.
+//	-merge
+//		Merge all the inputs into a single stream ordered by timestamp, instead of printing them one after another.
+//		Directories stand for all the files they contain. Each line is tagged with the name of its file: raw lines
+//		are prefixed with the name and a colon, and the other formats gain a "source" field. Logs written with
+//		nblog.TimeOnlyFormat take their dates from MMDDYY file names.
+//	-tz ZONE
+//		Time zone of timestamps that don't include one, such as "UTC" or "America/Chicago". This applies to the log
+//		files as well as to -since and -until. The default is the local time zone.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"sweetkennedy.net/nblog"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options holds the parsed command line.
+type options struct {
+	filter   filter
+	format   string
+	layouts  []string
+	location *time.Location
+	merge    bool
+	files    []string
+}
+
+func parseArgs(args []string, stderr io.Writer) (*options, error) {
+	opts := &options{location: time.Local}
+	flags := flag.NewFlagSet("nblogcat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.format, "format", "raw", "output `format`: raw, jsonl, csv, or text")
+	flags.Func("level", "print records at or above `level`", func(s string) error {
+		var level slog.Level
+		err := level.UnmarshalText([]byte(s))
+		if err == nil {
+			opts.filter = append(opts.filter, minLevel(level))
+		}
+		return err
+	})
+	var sinceText, untilText string
+	flags.StringVar(&sinceText, "since", "", "print records at or after `time`")
+	flags.StringVar(&untilText, "until", "", "print records before `time`")
+	var pidList []string
+	flags.Func("pid", "print records from process `pid` (repeatable)", func(s string) error {
+		pidList = append(pidList, strings.Split(s, ",")...)
+		return nil
+	})
+	flags.Func("caller", "print records whose caller matches `regexp`", func(s string) error {
+		re, err := regexp.Compile(s)
+		if err == nil {
+			opts.filter = append(opts.filter, caller(re))
+		}
+		return err
+	})
+	flags.Func("where", "print records whose attributes satisfy `predicate` (repeatable)", func(s string) error {
+		p, err := parseAttrPredicate(s)
+		if err == nil {
+			opts.filter = append(opts.filter, p)
+		}
+		return err
+	})
+	flags.Func("layout", "timestamp `layout` of the input (repeatable)", func(s string) error {
+		opts.layouts = append(opts.layouts, s)
+		return nil
+	})
+	flags.BoolVar(&opts.merge, "merge", false, "merge the inputs by timestamp")
+	flags.Func("tz", "time `zone` of the input timestamps", func(s string) error {
+		loc, err := time.LoadLocation(s)
+		opts.location = loc
+		return err
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := opts.addTimeFilter(sinceText, since); err != nil {
+		_, _ = fmt.Fprintln(flags.Output(), "invalid value for -since:", err)
+		return nil, err
+	}
+	if err := opts.addTimeFilter(untilText, until); err != nil {
+		_, _ = fmt.Fprintln(flags.Output(), "invalid value for -until:", err)
+		return nil, err
+	}
+	if len(pidList) > 0 {
+		opts.filter = append(opts.filter, pids(pidList))
+	}
+	opts.files = flags.Args()
+	if len(opts.files) == 0 {
+		opts.files = []string{"-"}
+	}
+	return opts, nil
+}
+
+// addTimeFilter adds a predicate for the -since or -until flag. It runs after all the flags are parsed so that the
+// -tz flag applies regardless of its position.
+func (opts *options) addTimeFilter(s string, pred func(t time.Time) predicate) error {
+	if s == "" {
+		return nil
+	}
+	t, err := parseTime(s, opts.location)
+	if err == nil {
+		opts.filter = append(opts.filter, pred(t))
+	}
+	return err
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	out, err := newPrinter(opts.format, opts.merge, stdout)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "nblogcat:", err)
+		return 2
+	}
+	status := 0
+	if opts.merge {
+		status = mergeFiles(opts, out, stderr)
+	} else {
+		status = catFiles(opts, stdin, out, stderr)
+	}
+	if err := out.Flush(); err != nil {
+		_, _ = fmt.Fprintln(stderr, "nblogcat:", err)
+		status = 1
+	}
+	return status
+}
+
+func catFiles(opts *options, stdin io.Reader, out printer, stderr io.Writer) int {
+	status := 0
+	for _, name := range opts.files {
+		if err := catFile(name, stdin, opts, out); err != nil {
+			_, _ = fmt.Fprintln(stderr, "nblogcat:", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func catFile(name string, stdin io.Reader, opts *options, out printer) error {
+	if name == "-" {
+		return cat(nblog.NewScanner(stdin), opts, out)
+	}
+	f, err := nblog.OpenLog(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return cat(nblog.NewScanner(f), opts, out)
+}
+
+func mergeFiles(opts *options, out printer, stderr io.Writer) int {
+	m, err := nblog.NewMerger(opts.files...)
+	if err == nil {
+		defer m.Close()
+		err = cat(m, opts, out)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "nblogcat:", err)
+		return 1
+	}
+	return 0
+}
+
+// entrySource is the interface shared by [nblog.Scanner] and [nblog.Merger].
+type entrySource interface {
+	Scan() bool
+	Entry() (nblog.Entry, error)
+	Err() error
+}
+
+func cat(src entrySource, opts *options, out printer) error {
+	parser := nblog.LineParser{Layouts: opts.layouts, Location: opts.location}
+	switch src := src.(type) {
+	case *nblog.Scanner:
+		src.Parser = parser
+	case *nblog.Merger:
+		src.SetParser(parser)
+	}
+	_, raw := out.(*rawPrinter)
+	// printing records, for each source, whether its most recent record passed the filter, which decides the fate of
+	// any continuation lines.
+	printing := map[string]bool{}
+	for src.Scan() {
+		entry, err := src.Entry()
+		if err == nil {
+			printing[entry.Source] = opts.filter.match(&entry)
+		} else if !raw {
+			continue
+		}
+		if !printing[entry.Source] {
+			continue
+		}
+		if err := out.Print(&entry); err != nil {
+			return err
+		}
+	}
+	return src.Err()
+}
@@ -0,0 +1,186 @@
+package main
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+var input = heredoc.Doc(`
+	2024-11-22 15:00:07.398 [100] <INFO> Start: starting {"jobid": 42}
+	2024-11-22 15:00:08.000 [200] <DEBUG> Poll: polling
+	2024-11-22 15:00:09.500 [100] <ERROR> Write: write failed {"jobid": 42, "catalog": {"size": 2048}}
+	  continuation of the failure
+	2024-11-22 15:00:10.000 [200] <WARN> Poll: slow {"jobid": 7}
+`)
+
+func nblogcat(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	args = append([]string{"-tz", "UTC"}, args...)
+	status := run(args, strings.NewReader(input), &stdout, &stderr)
+	if stderr.Len() > 0 {
+		t.Log(stderr.String())
+	}
+	return stdout.String(), status
+}
+
+func TestRawFilters(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name     string
+		Args     []string
+		Expected string
+	}{
+		{"level", []string{"-level", "warn"}, heredoc.Doc(`
+			2024-11-22 15:00:09.500 [100] <ERROR> Write: write failed {"jobid": 42, "catalog": {"size": 2048}}
+			  continuation of the failure
+			2024-11-22 15:00:10.000 [200] <WARN> Poll: slow {"jobid": 7}
+		`)},
+		{"pid", []string{"-pid", "200"}, heredoc.Doc(`
+			2024-11-22 15:00:08.000 [200] <DEBUG> Poll: polling
+			2024-11-22 15:00:10.000 [200] <WARN> Poll: slow {"jobid": 7}
+		`)},
+		{"caller", []string{"-caller", "^S"}, heredoc.Doc(`
+			2024-11-22 15:00:07.398 [100] <INFO> Start: starting {"jobid": 42}
+		`)},
+		{"time", []string{"-since", "2024-11-22 15:00:08", "-until", "2024-11-22 15:00:09"}, heredoc.Doc(`
+			2024-11-22 15:00:08.000 [200] <DEBUG> Poll: polling
+		`)},
+		{"where", []string{"-where", "jobid=42", "-where", "catalog.size>1000"}, heredoc.Doc(`
+			2024-11-22 15:00:09.500 [100] <ERROR> Write: write failed {"jobid": 42, "catalog": {"size": 2048}}
+			  continuation of the failure
+		`)},
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			output, status := nblogcat(t, c.Args...)
+			g.Expect(status).To(Equal(0))
+			g.Expect(output).To(Equal(c.Expected))
+		})
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output, status := nblogcat(t, "-format", "jsonl", "-where", "catalog.size>0")
+	g.Expect(status).To(Equal(0))
+	g.Expect(output).To(MatchJSON(`{
+		"time": "2024-11-22T15:00:09.5Z", "level": "ERROR", "msg": "write failed",
+		"pid": "100", "caller": "Write", "attrs": {"jobid": 42, "catalog": {"size": 2048}}
+	}`))
+}
+
+func TestJSONLinesReservedKeys(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	var output strings.Builder
+	p, err := newPrinter("jsonl", false, &output)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(p.Print(&nblog.Entry{
+		Time:    time.Date(2024, 11, 22, 15, 0, 0, 0, time.UTC),
+		Level:   slog.LevelInfo,
+		Message: "hi",
+		Pid:     "12",
+		Caller:  "main",
+		Attrs: []slog.Attr{
+			slog.Int("pid", 99),
+			slog.String("msg", "inner"),
+			slog.String("caller", "x"),
+			slog.Int("level", 3),
+		},
+	})).To(Succeed())
+	g.Expect(p.Flush()).To(Succeed())
+	g.Expect(output.String()).To(MatchJSON(`{
+		"time": "2024-11-22T15:00:00Z", "level": "INFO", "msg": "hi", "pid": "12", "caller": "main",
+		"attrs": {"pid": 99, "msg": "inner", "caller": "x", "level": 3}
+	}`))
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output, status := nblogcat(t, "-format", "csv", "-pid", "100", "-level", "error")
+	g.Expect(status).To(Equal(0))
+	g.Expect(output).To(Equal(heredoc.Doc(`
+		time,pid,level,caller,message,attrs
+		2024-11-22T15:00:09.5Z,100,ERROR,Write,write failed,"{""jobid"":42,""catalog"":{""size"":2048}}"
+	`)))
+}
+
+func TestCSVReservedKeys(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	var output strings.Builder
+	p := newCSVPrinter(&output, false)
+	g.Expect(p.Print(&nblog.Entry{
+		Level:   slog.LevelInfo,
+		Message: "copied",
+		Attrs: []slog.Attr{
+			slog.String("msg", "<original>"),
+			slog.Group("level", slog.String("time", "later")),
+		},
+	})).To(Succeed())
+	g.Expect(p.Flush()).To(Succeed())
+	g.Expect(output.String()).To(Equal(heredoc.Doc(`
+		time,pid,level,caller,message,attrs
+		,,INFO,,copied,"{""msg"":""<original>"",""level"":{""time"":""later""}}"
+	`)))
+}
+
+func TestText(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output, status := nblogcat(t, "-format", "text", "-where", "jobid=7")
+	g.Expect(status).To(Equal(0))
+	g.Expect(output).To(Equal(
+		"time=2024-11-22T15:00:10.000Z level=WARN msg=slow pid=200 caller=Poll attrs.jobid=7\n"))
+}
+
+func TestBadArguments(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	_, status := nblogcat(t, "-format", "xml")
+	g.Expect(status).To(Equal(2))
+	_, status = nblogcat(t, "-where", "nonsense")
+	g.Expect(status).To(Equal(2))
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "112224_00001.log")
+	second := filepath.Join(dir, "112224_00002.log")
+	g.Expect(os.WriteFile(first, []byte("15:00:02.000 [1] <INFO> f: b\n"), 0o600)).To(Succeed())
+	g.Expect(os.WriteFile(second, []byte("15:00:01.000 [2] <INFO> f: a\n"), 0o600)).To(Succeed())
+
+	output, status := nblogcat(t, "-merge", dir)
+	g.Expect(status).To(Equal(0))
+	g.Expect(output).To(Equal(second + ":15:00:01.000 [2] <INFO> f: a\n" + first + ":15:00:02.000 [1] <INFO> f: b\n"))
+
+	output, status = nblogcat(t, "-merge", "-format", "text", first)
+	g.Expect(status).To(Equal(0))
+	g.Expect(output).To(Equal(
+		"time=2024-11-22T15:00:02.000Z level=INFO msg=b source=" + first + " pid=1 caller=f\n"))
+}
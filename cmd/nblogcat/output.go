@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"sweetkennedy.net/nblog"
+)
+
+// printer writes matching entries in one of the supported output formats.
+type printer interface {
+	Print(e *nblog.Entry) error
+	Flush() error
+}
+
+// newPrinter creates a printer for the given format. When tagged is true, the output identifies the source file of
+// each entry.
+func newPrinter(format string, tagged bool, w io.Writer) (printer, error) {
+	switch format {
+	case "raw":
+		return &rawPrinter{w, tagged}, nil
+	case "jsonl", "json":
+		return &handlerPrinter{slog.NewJSONHandler(w, allLevels())}, nil
+	case "text":
+		return &handlerPrinter{slog.NewTextHandler(w, allLevels())}, nil
+	case "csv":
+		return newCSVPrinter(w, tagged), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// allLevels returns handler options that let records of every level through. The filtering has already happened.
+func allLevels() *slog.HandlerOptions {
+	return &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+}
+
+// rawPrinter prints lines exactly as they appeared in the input, optionally prefixed with their source file names.
+type rawPrinter struct {
+	w      io.Writer
+	tagged bool
+}
+
+func (p *rawPrinter) Print(e *nblog.Entry) error {
+	line := e.Line + "\n"
+	if p.tagged {
+		line = e.Source + ":" + line
+	}
+	_, err := io.WriteString(p.w, line)
+	return err
+}
+
+func (*rawPrinter) Flush() error {
+	return nil
+}
+
+// handlerPrinter converts entries back into records and passes them to one of the standard slog handlers.
+type handlerPrinter struct {
+	h slog.Handler
+}
+
+func (p *handlerPrinter) Print(e *nblog.Entry) error {
+	return p.h.Handle(context.Background(), record(e))
+}
+
+func (*handlerPrinter) Flush() error {
+	return nil
+}
+
+// record converts an entry to a record, keeping the host, program, process ID, and caller as attributes since slog has
+// no dedicated fields for them. The line's own attributes go in an "attrs" group, so that they can't collide with
+// those or with the record's time, level, and message.
+func record(e *nblog.Entry) slog.Record {
+	r := slog.NewRecord(e.Time, e.Level, e.Message, 0)
+	if e.Source != "" {
+		r.AddAttrs(slog.String("source", e.Source))
+	}
+	if e.Host != "" {
+		r.AddAttrs(slog.String("host", e.Host))
+	}
+	if e.Program != "" {
+		r.AddAttrs(slog.String("program", e.Program))
+	}
+	if e.Pid != "" {
+		r.AddAttrs(slog.String("pid", e.Pid))
+	}
+	if e.Caller != "" {
+		r.AddAttrs(slog.String("caller", e.Caller))
+	}
+	if len(e.Attrs) > 0 {
+		r.AddAttrs(slog.Attr{Key: "attrs", Value: slog.GroupValue(e.Attrs...)})
+	}
+	return r
+}
+
+// csvPrinter writes one row per entry with the attributes as a JSON object in the last column.
+type csvPrinter struct {
+	w      *csv.Writer
+	attrs  bytes.Buffer
+	header bool
+	tagged bool
+}
+
+func newCSVPrinter(w io.Writer, tagged bool) *csvPrinter {
+	return &csvPrinter{w: csv.NewWriter(w), tagged: tagged}
+}
+
+func (p *csvPrinter) Print(e *nblog.Entry) error {
+	if !p.header {
+		p.header = true
+		if err := p.write("source", "time", "pid", "level", "caller", "message", "attrs"); err != nil {
+			return err
+		}
+	}
+	timestamp := e.TimeText
+	if !e.Time.IsZero() {
+		timestamp = e.Time.Format(time.RFC3339Nano)
+	}
+	attrs, err := p.attrsJSON(e.Attrs)
+	if err != nil {
+		return err
+	}
+	return p.write(e.Source, timestamp, e.Pid, e.Level.String(), e.Caller, e.Message, attrs)
+}
+
+// write writes one row. The first field is the source, which only appears in tagged output.
+func (p *csvPrinter) write(fields ...string) error {
+	if !p.tagged {
+		fields = fields[1:]
+	}
+	return p.w.Write(fields)
+}
+
+// attrsJSON renders attributes as a compact JSON object, with groups as nested objects.
+func (p *csvPrinter) attrsJSON(attrs []slog.Attr) (string, error) {
+	if len(attrs) == 0 {
+		return "", nil
+	}
+	p.attrs.Reset()
+	stream := jsoniter.NewStream(attrsAPI, &p.attrs, attrsBufferSize)
+	writeAttrsObject(stream, attrs)
+	if err := stream.Flush(); err != nil {
+		return "", err
+	}
+	if stream.Error != nil {
+		return "", stream.Error
+	}
+	return p.attrs.String(), nil
+}
+
+// attrsAPI encodes attribute values the way [slog.JSONHandler] would, without escaping HTML characters.
+var attrsAPI = jsoniter.Config{EscapeHTML: false}.Froze()
+
+const attrsBufferSize = 256 // size is arbitrary
+
+func writeAttrsObject(stream *jsoniter.Stream, attrs []slog.Attr) {
+	stream.WriteObjectStart()
+	for i, a := range attrs {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(a.Key)
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			writeAttrsObject(stream, v.Group())
+		} else {
+			stream.WriteVal(v.Any())
+		}
+	}
+	stream.WriteObjectEnd()
+}
+
+func (p *csvPrinter) Flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
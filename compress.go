@@ -0,0 +1,148 @@
+package nblog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how a [RotatingFile] compresses the log files it has finished writing.
+type Compression int
+
+const (
+	// CompressNone leaves rotated files uncompressed. This is the default.
+	CompressNone Compression = iota
+	// CompressGzip compresses rotated files with gzip, adding the extension ".gz".
+	CompressGzip
+	// CompressZstd compresses rotated files with Zstandard, adding the extension ".zst".
+	CompressZstd
+)
+
+// Magic numbers at the start of compressed files.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// extension returns the file name extension for the compression method.
+func (c Compression) extension() string {
+	switch c {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// writer wraps w in a compressor.
+func (c Compression) writer(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, errors.New("nblog: no compression method")
+	}
+}
+
+// OpenLog opens a log file for reading. If the file is compressed with gzip or Zstandard, as by [RotatingFile], the
+// reader decompresses it transparently; the compression is recognized from the file's contents, not its name.
+func OpenLog(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// logReader reads a possibly compressed file and closes both the decompressor and the file.
+type logReader struct {
+	io.Reader
+	closers []func() error
+}
+
+func (r *logReader) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c())
+	}
+	return errors.Join(errs...)
+}
+
+func decompress(f *os.File) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(f)
+	const magicLength = 4
+	head, _ := buffered.Peek(magicLength)
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return &logReader{zr, []func() error{zr.Close, f.Close}}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return &logReader{zr, []func() error{func() error { zr.Close(); return nil }, f.Close}}, nil
+	default:
+		return &logReader{buffered, []func() error{f.Close}}, nil
+	}
+}
+
+// compressFile compresses the named file to a new file with the method's extension added, preserving its modification
+// time, and then removes the original. It returns the name of the compressed file.
+func compressFile(name string, c Compression) (string, error) {
+	target := name + c.extension()
+	temp := target + ".tmp"
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if err := copyCompressed(name, temp, c, info.Mode().Perm()); err != nil {
+		_ = os.Remove(temp)
+		return "", err
+	}
+	if err := os.Chtimes(temp, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(temp)
+		return "", err
+	}
+	if err := os.Rename(temp, target); err != nil {
+		_ = os.Remove(temp)
+		return "", err
+	}
+	return target, os.Remove(name)
+}
+
+func copyCompressed(source, target string, c Compression, perm os.FileMode) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	zw, err := c.writer(dst)
+	if err != nil {
+		_ = dst.Close()
+		return err
+	}
+	_, err = io.Copy(zw, src)
+	return errors.Join(err, zw.Close(), dst.Close())
+}
@@ -0,0 +1,209 @@
+package nblog
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Console selects whether a handler formats its output for an interactive terminal. In console mode, the layout is
+// the same as usual, but severity labels are colored by level, the timestamp and process ID are dimmed, attribute keys
+// are highlighted, and attribute tails that would run past the terminal's width are truncated or wrapped (see
+// [ConsoleWrap]). Console output is meant for people; the decorations make it unsuitable for [ParseLine].
+type Console int
+
+const (
+	// ConsoleOff produces plain output. This is the default.
+	ConsoleOff Console = iota
+	// ConsoleAuto enables console mode only when the destination is a terminal and the NO_COLOR environment variable
+	// is empty or unset.
+	ConsoleAuto
+	// ConsoleOn enables console mode regardless of the destination, for example when piping to "less -R".
+	ConsoleOn
+)
+
+// ANSI escape sequences used in console mode.
+const (
+	styleReset  = "\x1b[0m"
+	styleDim    = "\x1b[2m"
+	styleKey    = "\x1b[36m"
+	styleDebug  = "\x1b[34m"
+	styleInfo   = "\x1b[32m"
+	styleWarn   = "\x1b[33m"
+	styleError  = "\x1b[1;31m"
+	ellipsis    = "…"
+	wrapIndent  = "    "
+	escapeStart = '\x1b'
+)
+
+// ConsoleMode configures whether the handler decorates its output for a terminal.
+func ConsoleMode(mode Console) Option {
+	return func(h slog.Handler) {
+		base(h).console = mode
+	}
+}
+
+// ConsoleWidth sets the width, in columns, to which console mode fits each line. By default, the handler asks the
+// terminal for its current width, falling back to the COLUMNS environment variable. Zero or less means there is no
+// limit.
+func ConsoleWidth(columns int) Option {
+	return func(h slog.Handler) {
+		base(h).consoleWidth = columns
+	}
+}
+
+// ConsoleWrap selects what console mode does with attribute tails that don't fit on the line. By default, they are
+// truncated and marked with an ellipsis. With wrap set to true, they continue on indented lines instead.
+func ConsoleWrap(wrap bool) Option {
+	return func(h slog.Handler) {
+		base(h).consoleWrap = wrap
+	}
+}
+
+// setUpConsole decides, once all the options are known, whether console mode is in effect.
+func (h *baseHandler) setUpConsole() {
+	switch h.console {
+	case ConsoleOn:
+		h.color = true
+	case ConsoleAuto:
+		h.color = os.Getenv("NO_COLOR") == "" && isTerminal(h.destination)
+	default:
+		h.color = false
+	}
+	if f, ok := h.destination.(*os.File); ok && h.color {
+		h.terminal = f
+	}
+}
+
+func isTerminal(w any) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint surrounds s with the given style when console mode is on.
+func (h *baseHandler) paint(style, s string) string {
+	if !h.color || s == "" {
+		return s
+	}
+	return style + s + styleReset
+}
+
+func levelStyle(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return styleError
+	case level >= slog.LevelWarn:
+		return styleWarn
+	case level >= slog.LevelInfo:
+		return styleInfo
+	default:
+		return styleDebug
+	}
+}
+
+// keyStyle is the style for attribute keys, or the empty string when console mode is off.
+func (h *baseHandler) keyStyle() string {
+	if !h.color {
+		return ""
+	}
+	return styleKey
+}
+
+// lineWidth returns the number of columns to fit each line into, or zero for no limit.
+func (h *baseHandler) lineWidth() int {
+	if h.consoleWidth != 0 {
+		return max(h.consoleWidth, 0)
+	}
+	if h.terminal != nil {
+		if width := terminalWidth(h.terminal); width > 0 {
+			return width
+		}
+	}
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return max(width, 0)
+}
+
+// fitTail shortens or wraps the attribute tail that begins at offset start of the buffer so that the last line of the
+// log message fits in the console width.
+func (h *baseHandler) fitTail(out *jsonStream, start int) {
+	width := h.lineWidth()
+	if !h.color || width == 0 {
+		return
+	}
+	buf := out.Buffer()
+	lineStart := strings.LastIndexByte(string(buf[:start]), '\n') + 1
+	available := width - visibleWidth(string(buf[lineStart:start]))
+	tail := string(buf[start:])
+	if visibleWidth(tail) <= available {
+		return
+	}
+	var fitted string
+	if h.consoleWrap {
+		fitted = wrapVisible(tail, available, width-len(wrapIndent))
+	} else {
+		head, _ := splitVisible(tail, max(available-1, 0))
+		fitted = head + styleReset + ellipsis
+	}
+	out.SetBuffer(append(buf[:start], fitted...))
+}
+
+// wrapVisible breaks s into a first piece of up to first columns and later pieces of up to rest columns, joining them
+// with newlines and indentation.
+func wrapVisible(s string, first, rest int) string {
+	var b strings.Builder
+	head, s := splitVisible(s, max(first, 0))
+	b.WriteString(head)
+	for s != "" {
+		head, s = splitVisible(s, max(rest, 1))
+		b.WriteString("\n" + wrapIndent + head)
+	}
+	return b.String()
+}
+
+// splitVisible splits s after n visible characters. Escape sequences don't count toward the width and are never split.
+func splitVisible(s string, n int) (string, string) {
+	visible := 0
+	for i := 0; i < len(s); {
+		if s[i] == escapeStart {
+			i += escapeLength(s[i:])
+			continue
+		}
+		if visible == n {
+			return s[:i], s[i:]
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		visible++
+	}
+	return s, ""
+}
+
+// visibleWidth counts the characters of s that occupy a column, ignoring escape sequences.
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == escapeStart {
+			i += escapeLength(s[i:])
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// escapeLength returns the length of the SGR escape sequence at the start of s.
+func escapeLength(s string) int {
+	end := strings.IndexByte(s, 'm')
+	if end < 0 {
+		return len(s)
+	}
+	return end + 1
+}
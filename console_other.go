@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package nblog
+
+import "os"
+
+// terminalWidth is unknown on this platform, so the handler falls back to the COLUMNS environment variable.
+func terminalWidth(*os.File) int {
+	return 0
+}
@@ -0,0 +1,103 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestConsoleColors(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output,
+		nblog.ConsoleMode(nblog.ConsoleOn),
+		nblog.ConsoleWidth(0),
+		nblog.ReplaceAttr(UniformOutput),
+	))
+
+	logger.Warn("message", slog.Int("a", 1))
+	logger.Error("failure")
+
+	g.Expect(output.Lines).To(Equal([]string{
+		"\x1b[2m2006-01-02 15:04:05.000\x1b[0m \x1b[2m[42]\x1b[0m \x1b[33m<WARN>\x1b[0m TestConsoleColors: message " +
+			"{\x1b[36m\"a\"\x1b[0m: 1}",
+		"\x1b[2m2006-01-02 15:04:05.000\x1b[0m \x1b[2m[42]\x1b[0m \x1b[1;31m<ERROR>\x1b[0m TestConsoleColors: failure",
+	}))
+}
+
+// stripEscapes removes the SGR escape sequences from console output so tests can check the layout.
+var regexpEscapes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripEscapes(s string) string {
+	return regexpEscapes.ReplaceAllString(s, "")
+}
+
+func TestConsoleTruncate(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output,
+		nblog.ConsoleMode(nblog.ConsoleOn),
+		nblog.ConsoleWidth(70),
+		nblog.ReplaceAttr(UniformOutput),
+	))
+
+	logger.Info("m", slog.String("long", "0123456789012345678901234567890123456789"))
+	logger.Info("m", slog.Int("s", 1))
+
+	g.Expect(stripEscapes(output.Lines[0])).To(Equal(
+		`2006-01-02 15:04:05.000 [42] <INFO> TestConsoleTruncate: m {"long": "…`))
+	g.Expect(stripEscapes(output.Lines[1])).To(HaveSuffix(`m {"s": 1}`))
+}
+
+func TestConsoleWrap(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output,
+		nblog.ConsoleMode(nblog.ConsoleOn),
+		nblog.ConsoleWidth(70),
+		nblog.ConsoleWrap(true),
+		nblog.ReplaceAttr(UniformOutput),
+	))
+
+	logger.Info("m", slog.String("long", "0123456789012345678901234567890123456789"))
+
+	g.Expect(stripEscapes(output.Lines[0])).To(Equal(
+		"2006-01-02 15:04:05.000 [42] <INFO> TestConsoleWrap: m {\"long\": \"01234\n" +
+			"    56789012345678901234567890123456789\"}"))
+}
+
+func TestConsoleAutoIsOffForNonTerminals(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	f, err := os.Create(t.TempDir() + "/log")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+	slog.New(nblog.New(f, nblog.ConsoleMode(nblog.ConsoleAuto))).Warn("message")
+
+	content, err := os.ReadFile(f.Name())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(content)).NotTo(ContainSubstring("\x1b"))
+}
+
+func TestConsoleRespectsNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	g := NewWithT(t)
+
+	// Standard error might or might not be a terminal, but with NO_COLOR set, the result is plain either way.
+	output := &LineBuffer{}
+	h := nblog.New(output, nblog.ConsoleMode(nblog.ConsoleAuto))
+	slog.New(h).Warn("message")
+	g.Expect(output.Lines[0]).NotTo(ContainSubstring("\x1b"))
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package nblog
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize matches the kernel's struct winsize.
+type winsize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// terminalWidth asks the terminal for its current width in columns. It returns zero if f isn't a terminal.
+func terminalWidth(f *os.File) int {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&ws))) //revive:disable-line:unsafe Required by ioctl.
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.cols)
+}
@@ -0,0 +1,208 @@
+package nblog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OnError registers a callback that receives the error whenever a log record can't be written to the destination,
+// even after any retries requested with [RetryWrites]. Without it, such errors are only returned from
+// [slog.Handler.Handle], and [slog.Logger] discards them. The callback runs synchronously on the logging goroutine and
+// must not log through the same handler.
+func OnError(fn func(error)) Option {
+	return func(h slog.Handler) {
+		base(h).onError = fn
+	}
+}
+
+// Fallback configures a secondary destination, such as [os.Stderr], that receives each record the primary destination
+// failed to accept. Records sent to the fallback still count as lost from the primary destination; once the primary
+// destination accepts writes again, the handler writes a notice there saying how many records it missed.
+func Fallback(w io.Writer) Option {
+	return func(h slog.Handler) {
+		base(h).fallback = w
+	}
+}
+
+// RetryWrites makes the handler retry a failed write up to retries more times before giving up on a record. It waits
+// for backoff before the first retry and doubles the wait before each subsequent one. The waits happen on the logging
+// goroutine, so keep them short.
+func RetryWrites(retries int, backoff time.Duration) Option {
+	return func(h slog.Handler) {
+		base(h).retries = retries
+		base(h).backoff = backoff
+	}
+}
+
+// LevelWriter is implemented by destinations that want to know the severity of each log line, such as
+// [SyslogWriter]. When the destination passed to [New] implements it, the handler calls WriteLevel instead of Write,
+// still exactly once per record.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level slog.Level, p []byte) (int, error)
+}
+
+// RecordWriter is implemented by destinations that want the structure of each record along with its rendered line,
+// such as [JournalWriter]. When the destination passed to [New] implements it, the handler calls WriteRecord instead of
+// Write or WriteLevel, still exactly once per record.
+type RecordWriter interface {
+	LevelWriter
+	WriteRecord(info RecordInfo, p []byte) (int, error)
+}
+
+// RecordInfo describes the record behind a line passed to a [RecordWriter].
+type RecordInfo struct {
+	Level slog.Level
+	// PC is the program counter of the record's caller, or zero if it has none, as in [slog.Record].
+	PC uintptr
+	// Attrs holds the record's attributes, including those added with [slog.Handler.WithAttrs], after any
+	// [ReplaceAttrFunc] callbacks. Attributes in groups, including those opened with [slog.Handler.WithGroup], are nested
+	// in group attributes. Size limits don't apply, so Attrs may hold attributes that the line lacks.
+	Attrs []slog.Attr
+}
+
+// SerializeWrites makes the handler hold a lock while writing to the destination, so that concurrent log calls never
+// interleave their output, even if the destination isn't safe for concurrent use. The lock is shared by all the
+// handlers derived from this one through [slog.Handler.WithAttrs] and [slog.Handler.WithGroup]. Destinations created by
+// this package, such as [File], are already safe for concurrent use and don't need this option.
+func SerializeWrites(serialize bool) Option {
+	return func(h slog.Handler) {
+		if serialize {
+			base(h).writeLock = &sync.Mutex{}
+		} else {
+			base(h).writeLock = nil
+		}
+	}
+}
+
+// emit sends a rendered log line to the destination, applying the retry, error-reporting, and fallback policies. If
+// earlier records were lost, a notice about them goes out in the same write as line, except for a [LevelWriter]
+// destination, which gets the notice as a separate message with its own severity.
+func (h *baseHandler) emit(line []byte, info RecordInfo) error {
+	if h.writeLock != nil {
+		h.writeLock.Lock()
+		defer h.writeLock.Unlock()
+	}
+	payload := line
+	// Claim the lost records, so that a concurrent write doesn't report them too.
+	lost := h.lost.Swap(0)
+	var err error
+	if lost > 0 {
+		notice := h.lostNotice(lost)
+		if _, ok := h.destination.(LevelWriter); ok {
+			err = h.writeWithRetry(notice, RecordInfo{Level: slog.LevelWarn, Attrs: []slog.Attr{slog.Uint64("lost", lost)}})
+			if err == nil {
+				h.stats.bytes.Add(uint64(len(notice)))
+				lost = 0
+			}
+		} else {
+			payload = append(notice, line...)
+		}
+	}
+	if err == nil {
+		err = h.writeWithRetry(payload, info)
+	}
+	if err == nil {
+		h.stats.written(info.Level, len(payload))
+		return nil
+	}
+	h.stats.writeErrors.Add(1)
+	h.lost.Add(lost + 1) // Give back the records that weren't reported, along with this one.
+	h.reportError(fmt.Errorf("nblog: writing log record: %w", err))
+	if h.fallback != nil {
+		if _, ferr := h.fallback.Write(line); ferr != nil {
+			h.reportError(fmt.Errorf("nblog: writing log record to fallback: %w", ferr))
+		}
+	}
+	return err
+}
+
+// writeWithRetry writes p to the destination, retrying with exponential backoff. After a partial write, only the
+// remaining bytes are retried.
+func (h *baseHandler) writeWithRetry(p []byte, info RecordInfo) error {
+	wait := h.backoff
+	for attempt := 0; ; attempt++ {
+		n, err := h.write(p, info)
+		if err == nil {
+			return nil
+		}
+		if attempt >= h.retries {
+			return err
+		}
+		p = p[n:]
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (h *baseHandler) write(p []byte, info RecordInfo) (int, error) {
+	switch w := h.destination.(type) {
+	case RecordWriter:
+		return w.WriteRecord(info, p)
+	case LevelWriter:
+		return w.WriteLevel(info.Level, p)
+	}
+	return h.destination.Write(p)
+}
+
+func (h *baseHandler) reportError(err error) {
+	if h.onError != nil {
+		h.onError(err)
+	}
+}
+
+// lostNotice renders the warning that tells readers of the primary log that records are missing.
+func (h *baseHandler) lostNotice(lost uint64) []byte {
+	r := slog.NewRecord(time.Now(), slog.LevelWarn,
+		fmt.Sprintf("lost %d records while destination was unavailable", lost), 0)
+	r.AddAttrs(slog.Uint64("lost", lost))
+	out := newJSONStream(h.encoding)
+	if err := h.render(out, r, recordAttributes(h, r)); err != nil {
+		return nil
+	}
+	return out.Buffer()
+}
+
+// collectedAttr is an attribute collected for a [RecordWriter], along with the groups that contain it.
+type collectedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// collect records an attribute for a [RecordWriter]. Groups aren't recorded themselves; their members are collected as
+// they're written.
+func (js *jsonStream) collect(groups []string, a slog.Attr) {
+	if js.collecting && a.Value.Kind() != slog.KindGroup {
+		js.collected = append(js.collected, collectedAttr{slices.Clone(groups), a})
+	}
+}
+
+// recordInfo describes the record rendered in out for a [RecordWriter].
+func recordInfo(out *jsonStream, record slog.Record) RecordInfo {
+	return RecordInfo{Level: record.Level, PC: record.PC, Attrs: nestAttrs(out.collected, 0)}
+}
+
+// nestAttrs rebuilds the group structure of collected attributes, starting at the given depth of their group paths.
+// Consecutive attributes in the same group share a group attribute.
+func nestAttrs(collected []collectedAttr, depth int) []slog.Attr {
+	var attrs []slog.Attr
+	for len(collected) > 0 {
+		if len(collected[0].groups) == depth {
+			attrs = append(attrs, collected[0].attr)
+			collected = collected[1:]
+			continue
+		}
+		group := collected[0].groups[depth]
+		n := 1
+		for n < len(collected) && len(collected[n].groups) > depth && collected[n].groups[depth] == group {
+			n++
+		}
+		attrs = append(attrs, slog.Attr{Key: group, Value: slog.GroupValue(nestAttrs(collected[:n], depth+1)...)})
+		collected = collected[n:]
+	}
+	return attrs
+}
@@ -0,0 +1,159 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"errors"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+var errUnavailable = errors.New("destination unavailable")
+
+// FlakyWriter is a [LineBuffer] that fails while Down is true, or for the next Failures calls.
+type FlakyWriter struct {
+	LineBuffer
+	Down     bool
+	Failures int
+	Calls    int
+}
+
+func (fw *FlakyWriter) Write(b []byte) (int, error) {
+	fw.Calls++
+	if fw.Down {
+		return 0, errUnavailable
+	}
+	if fw.Failures > 0 {
+		fw.Failures--
+		return 0, errUnavailable
+	}
+	return fw.LineBuffer.Write(b)
+}
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	primary := &FlakyWriter{Down: true}
+	fallback := &LineBuffer{}
+	var reported []error
+	logger := slog.New(nblog.New(primary,
+		nblog.OnError(func(err error) { reported = append(reported, err) }),
+		nblog.Fallback(fallback),
+	))
+
+	logger.Info("first")
+	logger.Info("second")
+	primary.Down = false
+	logger.Info("third")
+	logger.Info("fourth")
+
+	g.Expect(reported).To(HaveLen(2))
+	g.Expect(reported).To(HaveEach(MatchError(errUnavailable)))
+	g.Expect(fallback.Lines).To(HaveExactElements(
+		HaveSuffix(": first"),
+		HaveSuffix(": second"),
+	))
+	// The notice and the first successful record arrive in a single write.
+	g.Expect(primary.Lines).To(HaveExactElements(
+		MatchRegexp(`<WARN> lost 2 records while destination was unavailable \{"lost": 2\}\n.*: third$`),
+		HaveSuffix(": fourth"),
+	))
+}
+
+// SyncFlakyWriter is a [SyncLineBuffer] that fails for the first Failures calls, and is slow to succeed, so concurrent
+// writes overlap. It's safe for concurrent use.
+type SyncFlakyWriter struct {
+	SyncLineBuffer
+	mu       sync.Mutex
+	Failures int
+}
+
+func (fw *SyncFlakyWriter) Write(b []byte) (int, error) {
+	fw.mu.Lock()
+	failing := fw.Failures > 0
+	if failing {
+		fw.Failures--
+	}
+	fw.mu.Unlock()
+	if failing {
+		return 0, errUnavailable
+	}
+	time.Sleep(time.Millisecond)
+	return fw.SyncLineBuffer.Write(b)
+}
+
+func TestConcurrentLostNotices(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	const failures = 50
+	primary := &SyncFlakyWriter{Failures: failures}
+	logger := slog.New(nblog.New(primary))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				logger.Info("record")
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Info("final")
+
+	// Every lost record is reported exactly once.
+	notice := regexp.MustCompile(`<WARN> lost (\d+) records while destination was unavailable`)
+	reported := 0
+	for _, line := range primary.Lines() {
+		for _, m := range notice.FindAllStringSubmatch(line, -1) {
+			n, err := strconv.Atoi(m[1])
+			g.Expect(err).ToNot(HaveOccurred())
+			reported += n
+		}
+	}
+	g.Expect(reported).To(Equal(failures))
+}
+
+func TestRetryWrites(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	primary := &FlakyWriter{Failures: 2}
+	var reported []error
+	h := nblog.New(primary,
+		nblog.OnError(func(err error) { reported = append(reported, err) }),
+		nblog.RetryWrites(2, time.Millisecond),
+	)
+
+	slog.New(h).Info("message")
+
+	g.Expect(reported).To(BeEmpty())
+	g.Expect(primary.Calls).To(Equal(3))
+	g.Expect(primary.Lines).To(HaveExactElements(HaveSuffix(": message")))
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	primary := &FlakyWriter{Down: true}
+	var reported []error
+	logger := slog.New(nblog.New(primary,
+		nblog.OnError(func(err error) { reported = append(reported, err) }),
+		nblog.RetryWrites(1, time.Millisecond),
+	))
+
+	logger.Info("message")
+
+	g.Expect(primary.Calls).To(Equal(2))
+	g.Expect(reported).To(HaveExactElements(MatchError(errUnavailable)))
+}
@@ -0,0 +1,151 @@
+package nblog
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// Duplicates selects what a handler does when the same key appears more than once in one JSON object of the attribute
+// tail, as happens when an attribute from [slog.Logger.With] is repeated on the record itself. The policy applies at
+// every level of nesting, to keys as they are after any [ReplaceAttr] callbacks, and to groups as well as plain
+// attributes.
+type Duplicates int
+
+const (
+	// DuplicatesKeepAll writes every attribute, even if that repeats a key. This is the default.
+	DuplicatesKeepAll Duplicates = iota
+	// DuplicatesLastWins keeps only the last attribute with each key, in the position where it occurred.
+	DuplicatesLastWins
+	// DuplicatesFirstWins keeps only the first attribute with each key.
+	DuplicatesFirstWins
+	// DuplicatesRename keeps every attribute, but adds a suffix to repeated keys to make them unique, so the second
+	// "id" becomes "id#2", the third becomes "id#3", and so on.
+	DuplicatesRename
+)
+
+// DuplicateKeys configures how the handler deals with repeated keys in the attribute tail.
+func DuplicateKeys(policy Duplicates) Option {
+	return func(h slog.Handler) {
+		base(h).duplicates = policy
+	}
+}
+
+// keyScope tracks the fields written to one JSON object so that duplicates can be found.
+type keyScope struct {
+	start      int // the offset just past the opening brace
+	fields     []fieldSpan
+	seen       map[string]int // the number of fields with each key
+	duplicated bool
+}
+
+// fieldSpan locates one field of an object in the buffer, not including the separator before it.
+type fieldSpan struct {
+	key        string
+	start, end int
+}
+
+func (js *jsonStream) trackingKeys() bool {
+	return js.duplicates != DuplicatesKeepAll && len(js.scopes) > 0
+}
+
+// openScope starts tracking the keys of an object that has just been opened.
+func (js *jsonStream) openScope() {
+	if js.duplicates == DuplicatesKeepAll {
+		return
+	}
+	js.scopes = append(js.scopes, &keyScope{
+		start: len(js.Buffer()),
+		seen:  map[string]int{},
+	})
+}
+
+// closeScope stops tracking the innermost object, which is about to be closed, and removes its unwanted duplicates.
+func (js *jsonStream) closeScope() {
+	if !js.trackingKeys() {
+		return
+	}
+	js.endField()
+	scope := js.scopes[len(js.scopes)-1]
+	js.scopes = js.scopes[:len(js.scopes)-1]
+	if scope.duplicated {
+		js.removeDuplicates(scope)
+	}
+}
+
+// endField marks the end of the previous field in the innermost object, if there is one.
+func (js *jsonStream) endField() {
+	if !js.trackingKeys() {
+		return
+	}
+	scope := js.scopes[len(js.scopes)-1]
+	if len(scope.fields) > 0 {
+		scope.fields[len(scope.fields)-1].end = len(js.Buffer())
+	}
+}
+
+// beginField records the start of a field in the innermost object and returns the key to write for it, which differs
+// from label when duplicates are renamed.
+func (js *jsonStream) beginField(label string) string {
+	if !js.trackingKeys() {
+		return label
+	}
+	scope := js.scopes[len(js.scopes)-1]
+	if js.duplicates == DuplicatesRename {
+		label = scope.uniqueKey(label)
+	}
+	scope.seen[label]++
+	scope.duplicated = scope.duplicated || scope.seen[label] > 1
+	scope.fields = append(scope.fields, fieldSpan{key: label, start: len(js.Buffer()), end: len(js.Buffer())})
+	return label
+}
+
+// uniqueKey returns label, or label with the smallest numeric suffix that hasn't been used in the object yet.
+func (s *keyScope) uniqueKey(label string) string {
+	if s.seen[label] == 0 {
+		return label
+	}
+	for n := 2; ; n++ {
+		candidate := label + "#" + strconv.Itoa(n)
+		if s.seen[candidate] == 0 {
+			return candidate
+		}
+	}
+}
+
+// forgetFields stops tracking fields that were rolled back from the buffer.
+func (js *jsonStream) forgetFields(length int) {
+	if !js.trackingKeys() {
+		return
+	}
+	scope := js.scopes[len(js.scopes)-1]
+	for len(scope.fields) > 0 && scope.fields[len(scope.fields)-1].start >= length {
+		scope.seen[scope.fields[len(scope.fields)-1].key]--
+		scope.fields = scope.fields[:len(scope.fields)-1]
+	}
+}
+
+// removeDuplicates rewrites the contents of an object with only the fields that the policy keeps.
+func (js *jsonStream) removeDuplicates(scope *keyScope) {
+	buf := js.Buffer()
+	var contents []byte
+	occurrences := map[string]int{}
+	for _, field := range scope.fields {
+		occurrences[field.key]++
+		if !js.keepField(occurrences[field.key], scope.seen[field.key]) {
+			continue
+		}
+		if len(contents) > 0 {
+			contents = append(contents, ", "...)
+		}
+		contents = append(contents, buf[field.start:field.end]...)
+	}
+	js.SetBuffer(append(buf[:scope.start], contents...))
+}
+
+// keepField reports whether the policy keeps the nth of total fields with the same key.
+func (js *jsonStream) keepField(n, total int) bool {
+	if js.duplicates == DuplicatesFirstWins {
+		return n == 1
+	}
+	return n == total
+}
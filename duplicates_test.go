@@ -0,0 +1,74 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		policy   nblog.Duplicates
+		expected string
+	}{
+		{
+			name:     "keep all",
+			policy:   nblog.DuplicatesKeepAll,
+			expected: `{"id": 1, "g": {"a": 1}, "id": 2, "g": {"a": 2, "a": 3}, "x": 4}`,
+		},
+		{
+			name:     "last wins",
+			policy:   nblog.DuplicatesLastWins,
+			expected: `{"id": 2, "g": {"a": 3}, "x": 4}`,
+		},
+		{
+			name:     "first wins",
+			policy:   nblog.DuplicatesFirstWins,
+			expected: `{"id": 1, "g": {"a": 1}, "x": 4}`,
+		},
+		{
+			name:     "rename",
+			policy:   nblog.DuplicatesRename,
+			expected: `{"id": 1, "g": {"a": 1}, "id#2": 2, "g#2": {"a": 2, "a#2": 3}, "x": 4}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			output := &LineBuffer{}
+			logger := slog.New(nblog.New(output, nblog.DuplicateKeys(tc.policy))).
+				With("id", 1, slog.Group("g", "a", 1))
+
+			logger.Info("message", "id", 2, slog.Group("g", "a", 2, "a", 3), "x", 4)
+
+			g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(": message " + tc.expected)))
+		})
+	}
+}
+
+func TestDuplicateKeysAfterReplaceAttr(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output,
+		nblog.DuplicateKeys(nblog.DuplicatesLastWins),
+		nblog.ReplaceAttr(func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == "user" {
+				a.Key = "name"
+			}
+			return a
+		}),
+	)).WithGroup("request")
+
+	logger.Info("message", "name", "first", "user", "second")
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: message {"request": {"name": "second"}}`)))
+}
@@ -0,0 +1,139 @@
+package nblog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+	"unsafe"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/modern-go/reflect2"
+)
+
+// typeEncoder converts values of one type, or of every type that implements an interface, before they're encoded.
+type typeEncoder struct {
+	typ    reflect.Type
+	encode func(any) any
+}
+
+// EncodeType registers a function that converts attribute values of type T into the form in which the handler writes
+// them. If T is an interface type, such as [fmt.Stringer] or a protobuf message interface, the function applies to
+// every type that implements it. The function's result is written as JSON: return a string or a number to write one,
+// a [json.RawMessage] to insert a JSON fragment verbatim, or any other value to have it encoded in the usual way. The
+// result must not be of type T itself.
+//
+// The function applies to values logged with [slog.Any] and its relatives, and to values nested within them, such as
+// struct fields and map elements. A function registered for a specific type takes precedence over one registered for
+// an interface; among interfaces, the first registered wins. Nil pointers and interfaces are written as null without
+// calling the function. An invalid JSON fragment, or a panic in the function, is handled like any other value that
+// can't be encoded; see [OnEncodingError].
+//
+// Registered functions take precedence over the methods listed in [MarshalPrecedence]. Some types from the standard
+// library have encoders built in, which apply unless overridden or unless the type has one of those methods: errors
+// are written as their messages, [url.URL], [net.HardwareAddr] and [net.IPMask] values as their String forms, and
+// durations nested within other values as their String forms, to match how the handler writes durations at the top
+// level.
+func EncodeType[T any](fn func(T) any) Option {
+	return func(h slog.Handler) {
+		b := base(h)
+		b.typeEncoders = append(b.typeEncoders, typeEncoder{
+			typ:    reflect.TypeFor[T](),
+			encode: func(v any) any { return fn(v.(T)) }, //revive:disable-line:unchecked-type-assertion
+		})
+	}
+}
+
+// builtinEncoders are the encoders described by [EncodeType] for common standard-library types.
+var builtinEncoders = []typeEncoder{
+	{reflect.TypeFor[error](), func(v any) any { return v.(error).Error() }},
+	{reflect.TypeFor[*url.URL](), func(v any) any { return v.(*url.URL).String() }},
+	{reflect.TypeFor[url.URL](), func(v any) any { u := v.(url.URL); return u.String() }},
+	{reflect.TypeFor[net.HardwareAddr](), func(v any) any { return v.(net.HardwareAddr).String() }},
+	{reflect.TypeFor[net.IPMask](), func(v any) any { return v.(net.IPMask).String() }},
+	{reflect.TypeFor[time.Duration](), func(v any) any { return v.(time.Duration).String() }},
+}
+
+// setUpEncoding creates the handler's encoder configuration once all the options are known. Handlers with the default
+// configuration share one, so the encoders jsoniter caches for each type are built only once.
+func (h *baseHandler) setUpEncoding() {
+	if len(h.typeEncoders) == 0 && h.precedence == nil && !h.standardJSON {
+		h.encoding = defaultEncoding
+		return
+	}
+	precedence := h.precedence
+	if precedence == nil {
+		precedence = defaultPrecedence
+	}
+	h.encoding = newValueEncoding(h.typeEncoders, precedence, h.standardJSON)
+}
+
+// lookupEncoder finds the registered encoder for t, or returns nil.
+func (e *valueEncoding) lookupEncoder(t reflect.Type) *typeEncoder {
+	for i, enc := range e.encoders {
+		if enc.typ == t {
+			return &e.encoders[i]
+		}
+	}
+	for i, enc := range e.encoders {
+		if enc.typ.Kind() == reflect.Interface && t.Implements(enc.typ) {
+			return &e.encoders[i]
+		}
+	}
+	return nil
+}
+
+// lookupBuiltin finds the built-in encoder for t, or returns nil.
+func lookupBuiltin(t reflect.Type) *typeEncoder {
+	for i, enc := range builtinEncoders {
+		if enc.typ == t || (enc.typ.Kind() == reflect.Interface && t.Implements(enc.typ)) {
+			return &builtinEncoders[i]
+		}
+	}
+	return nil
+}
+
+// registeredEncoder writes a value after converting it with a typeEncoder.
+type registeredEncoder struct {
+	typ     reflect2.Type
+	encoder *typeEncoder
+}
+
+func (e *registeredEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	v := reflect.ValueOf(e.typ.UnsafeIndirect(ptr))
+	return !v.IsValid() || v.IsZero()
+}
+
+func (e *registeredEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	v := e.typ.UnsafeIndirect(ptr)
+	if isNil(v) {
+		stream.WriteNil()
+		return
+	}
+	result := e.encoder.encode(v)
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		stream.WriteVal(result)
+		return
+	}
+	if json.Valid(raw) {
+		stream.WriteRaw(string(raw))
+		return
+	}
+	writeErrorMarkerTo(stream, fmt.Errorf("invalid JSON from encoder for %s", e.typ))
+}
+
+func isNil(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
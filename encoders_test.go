@@ -0,0 +1,126 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+type jobID [4]byte
+
+type labeled interface {
+	Label() string
+}
+
+type volume struct {
+	Name string
+}
+
+func (v volume) Label() string {
+	return "vol:" + v.Name
+}
+
+type tape struct {
+	Barcode string
+}
+
+func (t tape) Label() string {
+	return "tape:" + t.Barcode
+}
+
+type marshaledError struct{}
+
+func (marshaledError) Error() string {
+	return "plain"
+}
+
+func (marshaledError) MarshalJSON() ([]byte, error) {
+	return []byte(`{"code":7}`), nil
+}
+
+func TestEncodeType(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output,
+		nblog.EncodeType(func(id jobID) any { return hex.EncodeToString(id[:]) }),
+		nblog.EncodeType(func(l labeled) any { return l.Label() }),
+		nblog.EncodeType(func(t tape) any { return json.RawMessage(fmt.Sprintf(`{"barcode":%q}`, t.Barcode)) }),
+		nblog.EncodeType(func(d time.Month) any { return json.RawMessage("{") }),
+	))
+	var nilVolume *volume
+
+	logger.Info("encoded",
+		"id", jobID{0xde, 0xad, 0xbe, 0xef},
+		"volume", volume{"v1"},
+		"tape", tape{"A00001"},
+		"nested", struct {
+			IDs     []jobID
+			Volume  *volume
+			Missing *volume
+		}{[]jobID{{1, 2, 3, 4}}, &volume{"v2"}, nilVolume},
+		"month", time.March,
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: encoded {"id": "deadbeef", "volume": "vol:v1", ` +
+		`"tape": {"barcode":"A00001"}, "nested": {"IDs":["01020304"],"Volume":"vol:v2","Missing":null}, ` +
+		`"month": "!ERROR:invalid JSON from encoder for time.Month"}`)))
+}
+
+func TestBuiltinEncoders(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output))
+	u, err := url.Parse("https://example.com/a?b=c")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	logger.Info("builtin",
+		"err", fmt.Errorf("wrapped: %w", errors.New("inner")),
+		"url", u,
+		"mac", net.HardwareAddr{0, 0x1b, 0x2c, 0x3d, 0x4e, 0x5f},
+		"mask", net.CIDRMask(24, 32),
+		"ip", net.ParseIP("10.0.0.1"),
+		"timeouts", []time.Duration{time.Second, 90 * time.Minute},
+		"marshaled", marshaledError{},
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: builtin {"err": "wrapped: inner", ` +
+		`"url": "https://example.com/a?b=c", "mac": "00:1b:2c:3d:4e:5f", "mask": "ffffff00", "ip": "10.0.0.1", ` +
+		`"timeouts": ["1s","1h30m0s"], "marshaled": {"code":7}}`)))
+}
+
+func TestStandardLibraryMarshalers(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output))
+	huge, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	g.Expect(ok).To(BeTrue())
+
+	logger.Info("marshalers",
+		"ip", net.ParseIP("2001:db8::1"),
+		"big", huge,
+		"nested", struct {
+			Addr  net.IP
+			Count *big.Int
+		}{net.IPv4(192, 168, 1, 2), big.NewInt(-42)},
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: marshalers {"ip": "2001:db8::1", ` +
+		`"big": 123456789012345678901234567890, "nested": {"Addr":"192.168.1.2","Count":-42}}`)))
+}
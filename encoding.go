@@ -0,0 +1,277 @@
+package nblog
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/modern-go/reflect2"
+)
+
+// errorMarkerPrefix starts the string written in place of a value that can't be encoded.
+const errorMarkerPrefix = "!ERROR:"
+
+// OnEncodingError registers a callback that receives each attribute value that couldn't be encoded as JSON, such as a
+// channel, a function, a cyclic structure, a floating-point NaN or infinity, or a value whose MarshalJSON method
+// failed or panicked. Such a value never costs the rest of the record: NaN and infinities are written as the strings
+// "NaN", "+Inf", and "-Inf", and other values as a string describing the failure, such as
+// "!ERROR:unsupported type chan int". The callback is meant for debugging. It runs synchronously on the logging
+// goroutine, once for each failure, and must not log through the same handler.
+func OnEncodingError(fn func(key string, err error)) Option {
+	return func(h slog.Handler) {
+		base(h).onEncodingError = fn
+	}
+}
+
+// encodingFailed records a problem with a value being encoded, to be reported when the attribute is finished.
+func (js *jsonStream) encodingFailed(err error) {
+	js.encodingErrors = append(js.encodingErrors, err)
+}
+
+// reportEncodingErrors passes the problems found while writing the attribute to the callback.
+func (js *jsonStream) reportEncodingErrors(key string) {
+	if js.onEncodingError != nil {
+		for _, err := range js.encodingErrors {
+			js.onEncodingError(key, err)
+		}
+	}
+	js.encodingErrors = js.encodingErrors[:0]
+}
+
+// writeSafeFloat writes a float, or a string for a value that JSON can't represent.
+func (js *jsonStream) writeSafeFloat(f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		s := strconv.FormatFloat(f, 'g', -1, bits)
+		if math.IsInf(f, 1) {
+			s = "+Inf"
+		}
+		js.encodingFailed(fmt.Errorf("unsupported value %s", s))
+		js.stream.WriteString(s)
+		return
+	}
+	if bits == 32 { //revive:disable-line:add-constant
+		js.stream.WriteFloat32(float32(f))
+	} else {
+		js.stream.WriteFloat64(f)
+	}
+}
+
+// writeAnyValue writes an arbitrary value, replacing it with a marker string if it can't be encoded.
+func (js *jsonStream) writeAnyValue(val any) {
+	m := js.mark()
+	defer func() {
+		if r := recover(); r != nil {
+			js.rollback(m)
+			js.writeErrorMarker(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	js.stream.WriteVal(val)
+	if err := js.stream.Error; err != nil {
+		if js.cycleErr != nil {
+			err = js.cycleErr
+		}
+		js.stream.Error, js.cycleErr = nil, nil
+		js.rollback(m)
+		js.writeErrorMarker(err)
+	}
+}
+
+func (js *jsonStream) writeErrorMarker(err error) {
+	js.encodingFailed(err)
+	js.stream.WriteString(errorMarkerPrefix + err.Error())
+}
+
+// writeErrorMarkerTo writes a marker from within a jsoniter encoder, recording the problem if the stream belongs to a
+// jsonStream.
+func writeErrorMarkerTo(stream *jsoniter.Stream, err error) {
+	if js, ok := stream.Attachment.(*jsonStream); ok {
+		js.writeErrorMarker(err)
+	} else {
+		stream.WriteString(errorMarkerPrefix + err.Error())
+	}
+}
+
+// safeEncoding is a jsoniter extension that encodes floats and unsupported kinds of values without failing.
+type safeEncoding struct {
+	jsoniter.DummyExtension
+}
+
+var (
+	float32Type = reflect2.TypeOf(float32(0))
+	float64Type = reflect2.TypeOf(float64(0))
+
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// CreateEncoder implements [jsoniter.Extension].
+func (*safeEncoding) CreateEncoder(typ reflect2.Type) jsoniter.ValEncoder {
+	switch {
+	case typ == float32Type:
+		return &floatEncoder{bits: 32}
+	case typ == float64Type:
+		return &floatEncoder{bits: 64}
+	case isMarshaler(typ.Type1()):
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return &unsupportedEncoder{typ: typ}
+	default:
+		return nil
+	}
+}
+
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// floatEncoder writes floats, with NaN and infinities as strings.
+type floatEncoder struct {
+	bits int
+}
+
+func (e *floatEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	if e.bits == 32 { //revive:disable-line:add-constant
+		return *(*float32)(ptr) == 0
+	}
+	return *(*float64)(ptr) == 0
+}
+
+func (e *floatEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	var f float64
+	if e.bits == 32 { //revive:disable-line:add-constant
+		f = float64(*(*float32)(ptr))
+	} else {
+		f = *(*float64)(ptr)
+	}
+	if js, ok := stream.Attachment.(*jsonStream); ok {
+		js.writeSafeFloat(f, e.bits)
+	} else {
+		stream.WriteFloat64(f)
+	}
+}
+
+// unsupportedEncoder writes a marker in place of a value that has no JSON representation.
+type unsupportedEncoder struct {
+	typ reflect2.Type
+}
+
+func (*unsupportedEncoder) IsEmpty(unsafe.Pointer) bool {
+	return false
+}
+
+func (e *unsupportedEncoder) Encode(_ unsafe.Pointer, stream *jsoniter.Stream) {
+	writeErrorMarkerTo(stream, fmt.Errorf("unsupported type %s", e.typ))
+}
+
+// cycleCandidates caches, for each type, whether a value of that type could refer back to itself.
+var cycleCandidates sync.Map
+
+// startDetectingCycles is the depth of nested references beyond which the encoder starts tracking them to find cycles,
+// like [encoding/json]. Shallower values, which are nearly all of them, cost nothing extra.
+const startDetectingCycles = 1000
+
+// cycleKey identifies a reference on the path from the top-level value. A slice is identified by its length as well
+// as its array, since a slice can hold a shorter slice of the same array without a cycle.
+type cycleKey struct {
+	ptr    uintptr
+	typ    reflect.Type
+	length int
+}
+
+// DecorateEncoder implements [jsoniter.Extension]. It guards the encoders of references that could lead back to the
+// value that holds them.
+func (*safeEncoding) DecorateEncoder(typ reflect2.Type, encoder jsoniter.ValEncoder) jsoniter.ValEncoder {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if mayCycle(typ.Type1()) {
+			return &cycleGuard{typ: typ, encoder: encoder}
+		}
+	}
+	return encoder
+}
+
+// cycleGuard counts the depth of nested references, and once it's deep enough, tracks them to stop a cycle from making
+// the encoder recurse forever. A cycle fails the stream, so the whole value is replaced with a marker.
+type cycleGuard struct {
+	typ     reflect2.Type
+	encoder jsoniter.ValEncoder
+}
+
+func (e *cycleGuard) IsEmpty(ptr unsafe.Pointer) bool {
+	return e.encoder.IsEmpty(ptr)
+}
+
+func (e *cycleGuard) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	js, ok := stream.Attachment.(*jsonStream)
+	if !ok {
+		e.encoder.Encode(ptr, stream)
+		return
+	}
+	js.encodeDepth++
+	defer func() { js.encodeDepth-- }()
+	if js.encodeDepth > startDetectingCycles {
+		v := reflect.ValueOf(e.typ.UnsafeIndirect(ptr))
+		if v.IsValid() && !v.IsNil() {
+			key := cycleKey{ptr: v.Pointer(), typ: v.Type()}
+			if v.Kind() == reflect.Slice {
+				key.length = v.Len()
+			}
+			if js.encodePath[key] {
+				if stream.Error == nil {
+					js.cycleErr = fmt.Errorf("cyclic value of type %s", v.Type())
+					stream.Error = js.cycleErr
+				}
+				return
+			}
+			if js.encodePath == nil {
+				js.encodePath = map[cycleKey]bool{}
+			}
+			js.encodePath[key] = true
+			defer delete(js.encodePath, key)
+		}
+	}
+	e.encoder.Encode(ptr, stream)
+}
+
+// mayCycle reports whether values of type t could contain references to themselves.
+func mayCycle(t reflect.Type) bool {
+	if cached, ok := cycleCandidates.Load(t); ok {
+		candidate, _ := cached.(bool)
+		return candidate
+	}
+	candidate := mayCycleWith(t, map[reflect.Type]bool{})
+	cycleCandidates.Store(t, candidate)
+	return candidate
+}
+
+// mayCycleWith implements mayCycle. A type that's already being examined refers to itself, so it may cycle.
+func mayCycleWith(t reflect.Type, examining map[reflect.Type]bool) bool {
+	if examining[t] {
+		return true
+	}
+	examining[t] = true
+	defer delete(examining, t)
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return mayCycleWith(t.Elem(), examining)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if f := t.Field(i); (f.IsExported() || f.Anonymous) && mayCycleWith(f.Type, examining) {
+				return true
+			}
+		}
+	}
+	return false
+}
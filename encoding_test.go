@@ -0,0 +1,105 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type brokenMarshaler struct{}
+
+func (brokenMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("broken")
+}
+
+type panickyMarshaler struct{}
+
+func (panickyMarshaler) MarshalJSON() ([]byte, error) {
+	panic("oops")
+}
+
+func TestUnencodableValues(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	var failures []string
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.OnEncodingError(func(key string, err error) {
+		failures = append(failures, key+": "+err.Error())
+	})))
+	cycle := &node{Name: "a"}
+	cycle.Next = &node{Name: "b", Next: cycle}
+	list := &node{Name: "a", Next: &node{Name: "b"}}
+
+	logger.Info("values",
+		"nan", math.NaN(),
+		"inf", math.Inf(-1),
+		"ch", make(chan int),
+		slog.Group("g", "fn", func() {}),
+		"nested", struct {
+			F  float64
+			C  any
+			OK float32
+		}{math.Inf(1), complex(1, 2), 1.5},
+		"cycle", cycle,
+		"list", list,
+		"broken", brokenMarshaler{},
+		"panicky", panickyMarshaler{},
+		"after", "fine",
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: values {"nan": "NaN", "inf": "-Inf", ` +
+		`"ch": "!ERROR:unsupported type chan int", "g": {"fn": "!ERROR:unsupported type func()"}, ` +
+		`"nested": {"F":"+Inf","C":"!ERROR:unsupported type complex128","OK":1.5}, ` +
+		`"cycle": "!ERROR:cyclic value of type *nblog_test.node", ` +
+		`"list": {"Name":"a","Next":{"Name":"b","Next":null}}, ` +
+		`"broken": "!ERROR:broken", ` +
+		`"panicky": "!ERROR:panic: oops", "after": "fine"}`)))
+	g.Expect(failures).To(ConsistOf(
+		"nan: unsupported value NaN",
+		"inf: unsupported value -Inf",
+		"ch: unsupported type chan int",
+		"fn: unsupported type func()",
+		"nested: unsupported value +Inf",
+		"nested: unsupported type complex128",
+		"cycle: cyclic value of type *nblog_test.node",
+		"broken: broken",
+		"panicky: panic: oops",
+	))
+}
+
+func TestCyclicValues(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output))
+	selfMap := map[string]any{"name": "m"}
+	selfMap["self"] = selfMap
+	selfSlice := []any{"s", nil}
+	selfSlice[1] = selfSlice
+	var deep *node
+	for range 1500 {
+		deep = &node{Name: "n", Next: deep}
+	}
+
+	logger.Info("values", "map", selfMap, "slice", selfSlice, "deep", deep, "after", "fine")
+
+	g.Expect(output.Lines).To(HaveExactElements(SatisfyAll(
+		ContainSubstring(`"map": "!ERROR:cyclic value of type map[string]interface {}", `),
+		ContainSubstring(`"slice": "!ERROR:cyclic value of type []interface {}", `),
+		ContainSubstring(`"deep": {"Name":"n","Next":{"Name":"n","Next":{`),
+		HaveSuffix(`"Next":null`+strings.Repeat("}", 1500)+`, "after": "fine"}`),
+	)))
+}
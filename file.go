@@ -0,0 +1,292 @@
+package nblog
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncPolicy selects when a [File] asks the operating system to commit its contents to stable storage with fsync.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves it to the operating system to decide when data reaches the disk. This is the default.
+	SyncNever SyncPolicy = iota
+	// SyncEachWrite syncs after every write to the file. With buffering, that's once per batch of records.
+	SyncEachWrite
+	// SyncPeriodically syncs at each interval set with [FileFlushInterval], or every second if there's no interval.
+	SyncPeriodically
+)
+
+// File is a log file opened by [OpenFile] for use as the destination of a [New] handler. It appends each record to the
+// end of the file, and it is safe for concurrent use by multiple goroutines. Unless disabled with [FileLocking], it
+// also takes an advisory lock on the file (flock(2), where the platform supports it) around each write so that several
+// processes can append to the same log without interleaving their lines.
+//
+// With [FileBuffer], the File collects records in memory and writes them in batches: when the buffer is full, when a
+// record at or above the level set with [FileFlushLevel] arrives, at each interval set with [FileFlushInterval], and
+// when [File.Flush] or [File.Close] is called. Each batch holds only whole records, so locking still keeps lines
+// intact. If writing a batch fails, its records are discarded. Errors from flushes that happen in the background are
+// returned by the next call to a File method.
+type File struct {
+	name       string
+	perm       fs.FileMode
+	locking    bool
+	bufferSize int
+	flushLevel slog.Level
+	interval   time.Duration
+	syncPolicy SyncPolicy
+
+	mu       sync.Mutex
+	f        *os.File
+	buf      []byte
+	unsynced bool  // whether data has been written since the last sync
+	err      error // from a background flush
+	done     chan struct{}
+}
+
+var _ LevelWriter = &File{}
+
+// FileOption is a function that can be passed to [OpenFile] to configure a new [File].
+type FileOption func(*File)
+
+// FilePermissions sets the permissions for a log file that doesn't exist yet. The default is 0644, before the umask.
+func FilePermissions(perm fs.FileMode) FileOption {
+	return func(f *File) {
+		f.perm = perm
+	}
+}
+
+// FileLocking controls whether each write takes an advisory lock on the file. The default is to lock, which makes it
+// safe for several processes to share a log file.
+func FileLocking(lock bool) FileOption {
+	return func(f *File) {
+		f.locking = lock
+	}
+}
+
+// FileBuffer sets the size, in bytes, of the buffer in which the file collects records before writing them. A record
+// bigger than the buffer is written directly. Zero or less means no buffering, which is the default.
+func FileBuffer(size int) FileOption {
+	return func(f *File) {
+		f.bufferSize = size
+	}
+}
+
+// FileFlushLevel sets the level at or above which a buffered file writes out its buffer immediately after receiving a
+// record. The default is [slog.LevelError], so errors are never left waiting in memory.
+func FileFlushLevel(level slog.Level) FileOption {
+	return func(f *File) {
+		f.flushLevel = level
+	}
+}
+
+// FileFlushInterval makes a buffered file write out its buffer at the given interval, so records don't wait long when
+// logging is slow. Zero or less means there's no periodic flush, which is the default.
+func FileFlushInterval(interval time.Duration) FileOption {
+	return func(f *File) {
+		f.interval = interval
+	}
+}
+
+// FileSync sets the policy for syncing the file to stable storage. The default is [SyncNever].
+func FileSync(policy SyncPolicy) FileOption {
+	return func(f *File) {
+		f.syncPolicy = policy
+	}
+}
+
+// OpenFile opens the named log file for appending, creating it if necessary.
+func OpenFile(name string, opts ...FileOption) (*File, error) {
+	const defaultPermissions = 0o644
+	file := &File{
+		name:       name,
+		perm:       defaultPermissions,
+		locking:    true,
+		bufferSize: 0,
+		flushLevel: slog.LevelError,
+		interval:   0,
+		syncPolicy: SyncNever,
+	}
+	for _, opt := range opts {
+		opt(file)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, file.perm)
+	if err != nil {
+		return nil, err
+	}
+	file.f = f
+	file.startBackgroundFlush()
+	return file, nil
+}
+
+// Name returns the name of the file as passed to [OpenFile].
+func (f *File) Name() string {
+	return f.name
+}
+
+// Write appends p to the file as a record at [slog.LevelInfo].
+func (f *File) Write(p []byte) (int, error) {
+	return f.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel appends p to the file, or to the buffer if the file is buffered. The level decides whether the buffer is
+// written out immediately; see [FileFlushLevel].
+func (f *File) WriteLevel(level slog.Level, p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError(); err != nil {
+		return 0, err
+	}
+	if f.bufferSize <= 0 || len(p) > f.bufferSize {
+		if err := f.flushLocked(); err != nil {
+			return 0, err
+		}
+		return len(p), f.writeOut(p)
+	}
+	if len(f.buf)+len(p) > f.bufferSize {
+		if err := f.flushLocked(); err != nil {
+			return 0, err
+		}
+	}
+	f.buf = append(f.buf, p...)
+	if level >= f.flushLevel || len(f.buf) == f.bufferSize {
+		return len(p), f.flushLocked()
+	}
+	return len(p), nil
+}
+
+// Flush writes out any buffered records.
+func (f *File) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError(); err != nil {
+		return err
+	}
+	return f.flushLocked()
+}
+
+// Sync writes out any buffered records and commits the file's contents to stable storage.
+func (f *File) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.takeError(); err != nil {
+		return err
+	}
+	if err := f.flushLocked(); err != nil {
+		return err
+	}
+	return f.syncLocked()
+}
+
+// Close writes out any buffered records, syncs the file unless the policy is [SyncNever], and closes it.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return os.ErrClosed
+	}
+	if f.done != nil {
+		close(f.done)
+	}
+	err := f.takeError()
+	if ferr := f.flushLocked(); err == nil {
+		err = ferr
+	}
+	if f.syncPolicy != SyncNever {
+		if serr := f.syncLocked(); err == nil {
+			err = serr
+		}
+	}
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	f.f = nil
+	return err
+}
+
+// takeError returns and clears the error from a background flush, or reports that the file is closed.
+func (f *File) takeError() error {
+	if f.f == nil {
+		return os.ErrClosed
+	}
+	err := f.err
+	f.err = nil
+	return err
+}
+
+func (f *File) flushLocked() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	err := f.writeOut(f.buf)
+	f.buf = f.buf[:0]
+	return err
+}
+
+// writeOut writes p to the file while holding the advisory lock, and syncs it if the policy calls for that.
+func (f *File) writeOut(p []byte) error {
+	if f.locking {
+		if err := lockFile(f.f); err != nil {
+			return &fs.PathError{Op: "lock", Path: f.name, Err: err}
+		}
+		defer func() { _ = unlockFile(f.f) }()
+	}
+	if _, err := f.f.Write(p); err != nil {
+		return err
+	}
+	f.unsynced = true
+	if f.syncPolicy == SyncEachWrite {
+		return f.syncLocked()
+	}
+	return nil
+}
+
+func (f *File) syncLocked() error {
+	if !f.unsynced {
+		return nil
+	}
+	f.unsynced = false
+	return f.f.Sync()
+}
+
+// startBackgroundFlush starts the goroutine that flushes and syncs the file periodically, if the options call for it.
+func (f *File) startBackgroundFlush() {
+	interval := f.interval
+	if interval <= 0 && f.syncPolicy == SyncPeriodically {
+		interval = time.Second
+	}
+	if interval <= 0 || (f.bufferSize <= 0 && f.syncPolicy != SyncPeriodically) {
+		return
+	}
+	f.done = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.done:
+				return
+			case <-ticker.C:
+				f.backgroundFlush()
+			}
+		}
+	}()
+}
+
+func (f *File) backgroundFlush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return
+	}
+	err := f.flushLocked()
+	if err == nil && f.syncPolicy == SyncPeriodically {
+		err = f.syncLocked()
+	}
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package nblog
+
+import "os"
+
+// lockFile does nothing on platforms without flock. Appends from a single process are still serialized by [File].
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,193 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// OverlapDetector is a writer that records whether any two calls to Write were ever in progress at the same time.
+type OverlapDetector struct {
+	active  atomic.Int32
+	overlap atomic.Bool
+	writes  atomic.Int32
+}
+
+func (d *OverlapDetector) Write(b []byte) (int, error) {
+	if d.active.Add(1) > 1 {
+		d.overlap.Store(true)
+	}
+	time.Sleep(time.Millisecond)
+	d.active.Add(-1)
+	d.writes.Add(1)
+	return len(b), nil
+}
+
+func TestSerializeWrites(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	detector := &OverlapDetector{}
+	h := nblog.New(detector, nblog.SerializeWrites(true))
+	handlers := []slog.Handler{h, h.WithAttrs([]slog.Attr{slog.Int("a", 1)}), h.WithGroup("g")}
+
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.New(handlers[i%len(handlers)]).Info("message", "i", i)
+		}()
+	}
+	wg.Wait()
+
+	g.Expect(detector.writes.Load()).To(BeEquivalentTo(12))
+	g.Expect(detector.overlap.Load()).To(BeFalse())
+}
+
+func TestFileAppend(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	name := filepath.Join(t.TempDir(), "101824_00001.log")
+	g.Expect(os.WriteFile(name, []byte("existing\n"), 0o600)).To(Succeed())
+
+	// Two files opened separately stand in for two processes sharing a log.
+	var files []*nblog.File
+	for range 2 {
+		f, err := nblog.OpenFile(name)
+		g.Expect(err).ToNot(HaveOccurred())
+		files = append(files, f)
+	}
+	g.Expect(files[0].Name()).To(Equal(name))
+
+	padding := strings.Repeat("x", 1000)
+	var wg sync.WaitGroup
+	for i := range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.New(nblog.New(files[i%2])).Info("message", "i", i, "padding", padding)
+		}()
+	}
+	wg.Wait()
+	for _, f := range files {
+		g.Expect(f.Close()).To(Succeed())
+	}
+
+	content, err := os.ReadFile(name)
+	g.Expect(err).ToNot(HaveOccurred())
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	g.Expect(lines).To(HaveLen(41))
+	g.Expect(string(lines[0])).To(Equal("existing"))
+	for _, line := range lines[1:] {
+		g.Expect(string(line)).To(MatchRegexp(`<INFO> func1: message \{"i": \d+, "padding": "x{1000}"\}$`))
+	}
+}
+
+func TestFileClosed(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	f, err := nblog.OpenFile(filepath.Join(t.TempDir(), "log"), nblog.FileLocking(false))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(f.Close()).To(Succeed())
+
+	_, err = fmt.Fprintln(f, "late")
+	g.Expect(err).To(MatchError(os.ErrClosed))
+}
+
+func TestFileBuffer(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	name := filepath.Join(t.TempDir(), "log")
+	f, err := nblog.OpenFile(name, nblog.FileBuffer(4096), nblog.FileSync(nblog.SyncEachWrite))
+	g.Expect(err).ToNot(HaveOccurred())
+	h := nblog.New(f, nblog.Banner(true))
+	logger := slog.New(h).With("a", 1)
+	contents := func() []string {
+		b, err := os.ReadFile(name)
+		g.Expect(err).ToNot(HaveOccurred())
+		return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	}
+
+	logger.Info("buffered")
+	g.Expect(contents()).To(HaveExactElements(""))
+
+	logger.Error("flushes")
+	g.Expect(contents()).To(HaveExactElements(
+		ContainSubstring("program started"),
+		HaveSuffix("buffered {\"a\": 1}"),
+		HaveSuffix("flushes {\"a\": 1}"),
+	))
+
+	logger.Info("buffered again")
+	g.Expect(nblog.Flush(logger.Handler())).To(Succeed())
+	g.Expect(contents()).To(HaveLen(4))
+
+	logger.Info("last")
+	g.Expect(nblog.Close(logger.Handler())).To(Succeed())
+	g.Expect(contents()).To(HaveExactElements(
+		ContainSubstring("program started"),
+		HaveSuffix("buffered {\"a\": 1}"),
+		HaveSuffix("flushes {\"a\": 1}"),
+		HaveSuffix("buffered again {\"a\": 1}"),
+		HaveSuffix("last {\"a\": 1}"),
+		MatchRegexp(`<INFO> program stopping \{"uptime": ".*", "reason": "closed"\}$`),
+	))
+	g.Expect(nblog.Close(h)).To(MatchError(os.ErrClosed))
+	_, err = f.Write([]byte("late\n"))
+	g.Expect(err).To(MatchError(os.ErrClosed))
+}
+
+func TestCloseLeavesCallerFileOpen(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	name := filepath.Join(t.TempDir(), "log")
+	f, err := os.Create(name)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+	h := nblog.New(f)
+
+	slog.New(h).Info("logged")
+	g.Expect(nblog.Close(h)).To(Succeed())
+	_, err = fmt.Fprintln(f, "still open")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	b, err := os.ReadFile(name)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")).To(HaveExactElements(
+		HaveSuffix(": logged"),
+		Equal("still open"),
+	))
+}
+
+func TestFileFlushInterval(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	name := filepath.Join(t.TempDir(), "log")
+	f, err := nblog.OpenFile(name, nblog.FileBuffer(4096), nblog.FileFlushInterval(10*time.Millisecond),
+		nblog.FileSync(nblog.SyncPeriodically))
+	g.Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+
+	slog.New(nblog.New(f)).Info("message")
+	g.Eventually(func() (string, error) {
+		b, err := os.ReadFile(name)
+		return string(b), err
+	}).Should(HaveSuffix("message\n"))
+}
@@ -5,13 +5,14 @@ go 1.24.0
 require (
 	github.com/MakeNowJust/heredoc/v2 v2.0.1
 	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.18.0
+	github.com/modern-go/reflect2 v1.0.2
 	github.com/onsi/gomega v1.38.2
 )
 
 require (
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
-	github.com/modern-go/reflect2 v1.0.2 // indirect
 	go.yaml.in/yaml/v3 v3.0.4 // indirect
 	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
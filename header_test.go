@@ -0,0 +1,72 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestHostAndProgram(t *testing.T) {
+	t.Parallel()
+
+	pid := strconv.Itoa(os.Getpid())
+	hostname, _ := os.Hostname()
+	for _, tc := range []struct {
+		name    string
+		opts    []nblog.Option
+		header  string
+		host    string
+		program string
+	}{
+		{
+			name:    "both",
+			opts:    []nblog.Option{nblog.ShowHostname(true), nblog.ShowProgram(true)},
+			header:  " " + hostname + " nblog.test[" + pid + "] <INFO> ",
+			host:    hostname,
+			program: "nblog.test",
+		},
+		{
+			name:    "program only",
+			opts:    []nblog.Option{nblog.ShowProgram(true)},
+			header:  " nblog.test[" + pid + "] <INFO> ",
+			program: "nblog.test",
+		},
+		{
+			name: "replaced host",
+			opts: []nblog.Option{
+				nblog.ShowHostname(true),
+				nblog.TimestampFormat(nblog.TimeOnlyFormat),
+				nblog.ReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+					if len(groups) == 0 && a.Key == nblog.HostKey {
+						return slog.String(a.Key, "catalog-0")
+					}
+					return a
+				}),
+			},
+			header: " catalog-0 [" + pid + "] <INFO> ",
+			host:   "catalog-0",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			output := &LineBuffer{}
+			slog.New(nblog.New(output, tc.opts...)).Info("message", "a", 1)
+
+			g.Expect(output.Lines).To(HaveExactElements(ContainSubstring(tc.header)))
+			entry, err := nblog.ParseLine(output.Lines[0])
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(entry.Time).ToNot(BeZero())
+			g.Expect(entry.Host).To(Equal(tc.host))
+			g.Expect(entry.Program).To(Equal(tc.program))
+			g.Expect(entry.Pid).To(Equal(pid))
+			g.Expect(entry.Message).To(Equal("message"))
+		})
+	}
+}
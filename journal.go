@@ -0,0 +1,212 @@
+package nblog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultJournalSocket is where systemd-journald listens for messages in its native protocol.
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// JournalWriter is a destination for [New] that sends each log line to systemd-journald using the journal's native
+// protocol, so the record's structure remains queryable with journalctl. Each message carries these fields:
+//
+//   - MESSAGE: the legacy-formatted log line
+//   - PRIORITY: the syslog severity for the record's level, mapped as for [SyslogWriter.WriteLevel]
+//   - CODE_FUNC, CODE_FILE, and CODE_LINE: the caller's fully qualified function name and position, if it has one
+//   - SYSLOG_IDENTIFIER: the program name
+//   - one field per attribute of the record
+//
+// The caller and attributes come from the handler through [RecordWriter], so they don't depend on how the line is
+// formatted. Lines written with Write or WriteLevel carry only the first, second, and fourth fields.
+//
+// Attribute field names are the attribute's group path and key joined with underscores, converted to upper case, with
+// any characters journald doesn't allow replaced by underscores. For example, the attribute "size" inside the group
+// "catalog" becomes CATALOG_SIZE. Names that would collide with the fields above get the prefix "ATTR_". Groups and
+// arrays are flattened; other values appear as text.
+//
+// It is safe for concurrent use.
+type JournalWriter struct {
+	socket     string
+	identifier string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+var _ RecordWriter = &JournalWriter{}
+
+// JournalOption is a function that can be passed to [NewJournalWriter] to configure a new [JournalWriter].
+type JournalOption func(*JournalWriter)
+
+// JournalSocket sets the path of journald's socket. The default is [DefaultJournalSocket].
+func JournalSocket(path string) JournalOption {
+	return func(w *JournalWriter) {
+		w.socket = path
+	}
+}
+
+// JournalIdentifier sets the SYSLOG_IDENTIFIER field. The default is the base name of the program.
+func JournalIdentifier(id string) JournalOption {
+	return func(w *JournalWriter) {
+		w.identifier = id
+	}
+}
+
+// NewJournalWriter prepares to send messages to journald's socket, which must exist.
+func NewJournalWriter(opts ...JournalOption) (*JournalWriter, error) {
+	w := &JournalWriter{
+		socket:     DefaultJournalSocket,
+		identifier: filepath.Base(os.Args[0]),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if _, err := os.Stat(w.socket); err != nil {
+		return nil, err
+	}
+	// The socket stays unconnected because passing file descriptors for large messages isn't allowed on connected
+	// datagram sockets.
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: "", Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	w.addr = &net.UnixAddr{Name: w.socket, Net: "unixgram"}
+	return w, nil
+}
+
+// Write sends p as a message at [slog.LevelInfo].
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel sends p with the PRIORITY field derived from level.
+func (w *JournalWriter) WriteLevel(level slog.Level, p []byte) (int, error) {
+	return w.WriteRecord(RecordInfo{Level: level}, p)
+}
+
+// WriteRecord sends p, which should be a line rendered by a [New] handler, with fields for the record's level, caller,
+// and attributes. Messages too big for a single datagram are passed to journald through a temporary file, where the
+// platform allows.
+func (w *JournalWriter) WriteRecord(info RecordInfo, p []byte) (int, error) {
+	msg := w.encode(info, string(trimNewline(p)))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.conn.WriteToUnix(msg, w.addr); err != nil {
+		if !isMessageTooLarge(err) {
+			return 0, err
+		}
+		if err := sendJournalFile(w.conn, w.addr, msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to journald.
+func (w *JournalWriter) Close() error {
+	return w.conn.Close()
+}
+
+// journalReserved lists the fields the writer sets itself, which attributes mustn't override.
+var journalReserved = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"CODE_FUNC":         true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"SYSLOG_IDENTIFIER": true,
+}
+
+// encode builds a datagram in journald's native format.
+func (w *JournalWriter) encode(info RecordInfo, line string) []byte {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", line)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(info.Level)))
+	if w.identifier != "" {
+		writeJournalField(&buf, "SYSLOG_IDENTIFIER", w.identifier)
+	}
+	if info.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{info.PC}).Next()
+		writeJournalField(&buf, "CODE_FUNC", frame.Function)
+		writeJournalField(&buf, "CODE_FILE", frame.File)
+		writeJournalField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+	}
+	flattenJournalAttrs(&buf, "", info.Attrs)
+	return buf.Bytes()
+}
+
+func flattenJournalAttrs(buf *bytes.Buffer, prefix string, attrs []slog.Attr) {
+	for _, attr := range attrs {
+		name := prefix + attr.Key
+		if attr.Value.Kind() == slog.KindGroup {
+			flattenJournalAttrs(buf, name+"_", attr.Value.Group())
+			continue
+		}
+		field := journalFieldName(name)
+		if journalReserved[field] {
+			field = "ATTR_" + field
+		}
+		writeJournalField(buf, field, journalValue(attr.Value))
+	}
+}
+
+func journalValue(v slog.Value) string {
+	if v.Kind() != slog.KindAny && v.Kind() != slog.KindLogValuer {
+		return v.String()
+	}
+	b, err := json.Marshal(v.Any())
+	if err != nil {
+		return v.String()
+	}
+	return string(b)
+}
+
+// journalFieldName converts an attribute path into a valid journal field name: upper-case letters, digits, and
+// underscores, not starting with an underscore or digit, and at most 64 characters.
+func journalFieldName(name string) string {
+	const maxFieldName = 64
+	field := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	field = strings.TrimLeft(field, "_0123456789")
+	if field == "" {
+		field = "ATTR"
+	}
+	if len(field) > maxFieldName {
+		field = field[:maxFieldName]
+	}
+	return field
+}
+
+// writeJournalField appends one field. Values containing newlines use the binary form: the name, a newline, the
+// value's length as a little-endian 64-bit integer, and then the value.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		buf.WriteByte('\n')
+		_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,33 @@
+package nblog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+func isMessageTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendJournalFile passes a message that's too big for a datagram by writing it to an unlinked temporary file and
+// sending journald the file descriptor, as sd_journal_send does.
+func sendJournalFile(conn *net.UnixConn, addr *net.UnixAddr, msg []byte) error {
+	f, err := os.CreateTemp("/dev/shm", "nblog-journal-")
+	if err != nil {
+		f, err = os.CreateTemp("", "nblog-journal-")
+		if err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(msg); err != nil {
+		return err
+	}
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr)
+	return err
+}
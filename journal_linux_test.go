@@ -0,0 +1,45 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestJournalLargeMessage(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	conn, path := listenUnixgram(t)
+	w, err := nblog.NewJournalWriter(nblog.JournalSocket(path))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+
+	big := strings.Repeat("x", 4<<20)
+	slog.New(nblog.New(w)).Info("big", slog.String("payload", big))
+
+	buf := make([]byte, 4096)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(BeZero())
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	g.Expect(err).NotTo(HaveOccurred())
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	g.Expect(err).NotTo(HaveOccurred())
+	msg, err := io.ReadAll(f)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(parseJournalMessage(t, msg)).To(HaveKeyWithValue("PAYLOAD", big))
+}
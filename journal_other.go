@@ -0,0 +1,16 @@
+//go:build !linux
+
+package nblog
+
+import (
+	"errors"
+	"net"
+)
+
+func isMessageTooLarge(error) bool {
+	return false
+}
+
+func sendJournalFile(*net.UnixConn, *net.UnixAddr, []byte) error {
+	return errors.New("nblog: journal message too large")
+}
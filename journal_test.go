@@ -0,0 +1,92 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// parseJournalMessage decodes a datagram in journald's native protocol.
+func parseJournalMessage(t *testing.T, msg []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for len(msg) > 0 {
+		nl := bytes.IndexByte(msg, '\n')
+		if nl < 0 {
+			t.Fatalf("unterminated field %q", msg)
+		}
+		line := msg[:nl]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			msg = msg[nl+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(msg[nl+1 : nl+9])
+		fields[string(line)] = string(msg[nl+9 : nl+9+int(size)])
+		msg = msg[nl+9+int(size)+1:]
+	}
+	return fields
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	conn, path := listenUnixgram(t)
+	w, err := nblog.NewJournalWriter(nblog.JournalSocket(path), nblog.JournalIdentifier("bpbrm"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+	logger := slog.New(nblog.New(w))
+
+	logger.With(slog.Int("jobid", 42)).Warn("two\nlines",
+		slog.Group("catalog", slog.Int("size", 2048), slog.String("image-name", "client_1")),
+		slog.String("message", "collides"),
+		slog.Any("paths", []string{"/a", "/b"}),
+	)
+
+	fields := parseJournalMessage(t, []byte(readDatagram(t, conn)))
+	g.Expect(fields).To(And(
+		HaveKeyWithValue("MESSAGE", MatchRegexp(`<WARN> TestJournal: two\nlines \{`)),
+		HaveKeyWithValue("PRIORITY", "4"),
+		HaveKeyWithValue("CODE_FUNC", "sweetkennedy.net/nblog_test.TestJournal"),
+		HaveKeyWithValue("CODE_FILE", HaveSuffix("journal_test.go")),
+		HaveKey("CODE_LINE"),
+		HaveKeyWithValue("SYSLOG_IDENTIFIER", "bpbrm"),
+		HaveKeyWithValue("JOBID", "42"),
+		HaveKeyWithValue("CATALOG_SIZE", "2048"),
+		HaveKeyWithValue("CATALOG_IMAGE_NAME", "client_1"),
+		HaveKeyWithValue("ATTR_MESSAGE", "collides"),
+		HaveKeyWithValue("PATHS", `["/a","/b"]`),
+	))
+}
+
+func TestJournalUnparsableLine(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	conn, path := listenUnixgram(t)
+	w, err := nblog.NewJournalWriter(nblog.JournalSocket(path))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+	dropPid := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == nblog.PidKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	logger := slog.New(nblog.New(w, nblog.ReplaceAttr(dropPid), nblog.ShowHostname(true), nblog.ShowProgram(true)))
+
+	logger.WithGroup("job").Info("started", slog.Int("id", 7))
+
+	fields := parseJournalMessage(t, []byte(readDatagram(t, conn)))
+	g.Expect(fields).To(And(
+		HaveKeyWithValue("MESSAGE", Not(MatchRegexp(`\[\d*\]`))),
+		HaveKeyWithValue("CODE_FUNC", "sweetkennedy.net/nblog_test.TestJournalUnparsableLine"),
+		HaveKeyWithValue("JOB_ID", "7"),
+	))
+}
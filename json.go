@@ -1,20 +1,21 @@
 package nblog
 
 import (
+	"fmt"
 	"log/slog"
 
 	jsoniter "github.com/json-iterator/go"
 )
 
 func writeAttribute(out *jsonStream, h *baseHandler, groups []string, attr slog.Attr) {
-	attr.Value = attr.Value.Resolve()
+	attr.Value = h.resolve(attr.Value)
 	switch attr.Value.Kind() {
 	case slog.KindGroup:
 		writeGroup(out, h, groups, attr)
 	default:
 		write, ok := writeByKind[attr.Value.Kind()]
 		if !ok {
-			panic("No writer found for value")
+			write = writeUnsupported
 		}
 		write(out, attr)
 	}
@@ -22,7 +23,11 @@ func writeAttribute(out *jsonStream, h *baseHandler, groups []string, attr slog.
 
 func writeString(out *jsonStream, attr slog.Attr) {
 	out.WriteObjectField(attr.Key)
-	out.WriteString(attr.Value.String())
+	s := attr.Value.String()
+	if out.maxValue > 0 {
+		s = truncateString(s, out.maxValue)
+	}
+	out.WriteString(s)
 }
 
 func writeInt64(out *jsonStream, attr slog.Attr) {
@@ -37,7 +42,8 @@ func writeUint64(out *jsonStream, attr slog.Attr) {
 
 func writeFloat64(out *jsonStream, attr slog.Attr) {
 	out.WriteObjectField(attr.Key)
-	out.WriteFloat64(attr.Value.Float64())
+	out.writeSafeFloat(attr.Value.Float64(), 64) //revive:disable-line:add-constant
+	out.reportEncodingErrors(attr.Key)
 }
 
 func writeBool(out *jsonStream, attr slog.Attr) {
@@ -57,14 +63,25 @@ func writeTime(out *jsonStream, attr slog.Attr) {
 
 func writeAny(out *jsonStream, attr slog.Attr) {
 	out.WriteObjectField(attr.Key)
-	out.WriteVal(attr.Value.Any())
+	m := out.mark()
+	out.writeAnyValue(attr.Value.Any())
+	out.limitValue(m)
+	out.reportEncodingErrors(attr.Key)
 }
 
-func writeLogValuer(*jsonStream, slog.Attr) {
-	panic("Unexpected use of LogValuer instead of Value.Resolve")
+// writeUnsupported writes a marker for a value of a kind that has no writer, such as a [slog.LogValuer] that
+// [slog.Value.Resolve] gave up on.
+func writeUnsupported(out *jsonStream, attr slog.Attr) {
+	out.WriteObjectField(attr.Key)
+	out.writeErrorMarker(fmt.Errorf("unsupported kind %s", attr.Value.Kind()))
+	out.reportEncodingErrors(attr.Key)
 }
 
 func writeGroup(out *jsonStream, base *baseHandler, groups []string, attr slog.Attr) {
+	if attr.Key != "" && base.depthLimitReached(out) {
+		writeElidedGroup(out, attr)
+		return
+	}
 	if attr.Key != "" {
 		out.WriteObjectField(attr.Key)
 		out.WriteObjectStart()
@@ -77,36 +94,75 @@ func writeGroup(out *jsonStream, base *baseHandler, groups []string, attr slog.A
 }
 
 var writeByKind = map[slog.Kind]func(*jsonStream, slog.Attr){
-	slog.KindString:    writeString,
-	slog.KindInt64:     writeInt64,
-	slog.KindUint64:    writeUint64,
-	slog.KindFloat64:   writeFloat64,
-	slog.KindBool:      writeBool,
-	slog.KindDuration:  writeDuration,
-	slog.KindTime:      writeTime,
-	slog.KindAny:       writeAny,
-	slog.KindLogValuer: writeLogValuer,
+	slog.KindString:   writeString,
+	slog.KindInt64:    writeInt64,
+	slog.KindUint64:   writeUint64,
+	slog.KindFloat64:  writeFloat64,
+	slog.KindBool:     writeBool,
+	slog.KindDuration: writeDuration,
+	slog.KindTime:     writeTime,
+	slog.KindAny:      writeAny,
+	// A LogValuer that's still unresolved is written according to the precedence for its type.
+	slog.KindLogValuer: writeAny,
 }
 
 type jsonStream struct {
 	stream    *jsoniter.Stream
 	needComma bool
+	keyStyle  string // escape sequence to highlight object keys in console mode
+	maxValue  int    // the limit on the length of each value; zero for none
+
+	duplicates Duplicates
+	scopes     []*keyScope // the open objects, when tracking keys to find duplicates
+
+	depth   int  // the number of open objects
+	attrs   int  // the number of attributes written so far
+	dropped int  // the number of attributes removed because of size limits
+	full    bool // whether the line is too long for more attributes
+
+	encoding        *valueEncoding
+	collecting      bool            // whether to collect the attributes for a RecordWriter
+	collected       []collectedAttr // the attributes written so far, when collecting
+	onEncodingError func(key string, err error)
+	encodingErrors  []error // problems with the attribute being written, not yet reported
+
+	encodeDepth int               // the number of nested references being encoded, to find cycles
+	encodePath  map[cycleKey]bool // the references being encoded, once encodeDepth is deep enough
+	cycleErr    error             // the cycle found, before jsoniter prefixed it with the fields leading to it
 }
 
-func newJSONStream() *jsonStream {
+// streamMark records a position in a jsonStream to roll back to.
+type streamMark struct {
+	length    int
+	attrs     int
+	needComma bool
+}
+
+func newJSONStream(encoding *valueEncoding) *jsonStream {
 	const jsonBufferSize = 50 // size is arbitrary
-	return &jsonStream{
-		stream:    jsoniter.NewStream(jsoniter.Config{}.Froze(), nil, jsonBufferSize),
+	js := &jsonStream{
+		stream:    jsoniter.NewStream(encoding.api, nil, jsonBufferSize),
 		needComma: false,
+		encoding:  encoding,
 	}
+	js.stream.Attachment = js
+	return js
 }
 
 func (js *jsonStream) WriteObjectField(label string) {
+	js.endField()
 	if js.needComma {
 		js.stream.WriteMore()
 		js.stream.WriteRaw(" ")
 	}
-	js.stream.WriteObjectField(label)
+	label = js.beginField(label)
+	if js.keyStyle != "" {
+		js.stream.WriteRaw(js.keyStyle)
+		js.stream.WriteString(label)
+		js.stream.WriteRaw(styleReset + ":")
+	} else {
+		js.stream.WriteObjectField(label)
+	}
 	js.stream.WriteRaw(" ")
 	js.needComma = true
 }
@@ -114,21 +170,21 @@ func (js *jsonStream) WriteObjectField(label string) {
 func (js *jsonStream) WriteObjectStart() {
 	js.stream.WriteObjectStart()
 	js.needComma = false
+	js.depth++
+	js.openScope()
 }
 
 func (js *jsonStream) WriteObjectEnd() {
+	js.closeScope()
 	js.stream.WriteObjectEnd()
 	js.needComma = true
+	js.depth--
 }
 
 func (js *jsonStream) WriteBool(val bool) {
 	js.stream.WriteBool(val)
 }
 
-func (js *jsonStream) WriteFloat64(val float64) {
-	js.stream.WriteFloat64(val)
-}
-
 func (js *jsonStream) WriteInt64(val int64) {
 	js.stream.WriteInt64(val)
 }
@@ -145,10 +201,6 @@ func (js *jsonStream) WriteUint64(val uint64) {
 	js.stream.WriteUint64(val)
 }
 
-func (js *jsonStream) WriteVal(val any) {
-	js.stream.WriteVal(val)
-}
-
 func (js *jsonStream) Error() error {
 	return js.stream.Error
 }
@@ -156,3 +208,35 @@ func (js *jsonStream) Error() error {
 func (js *jsonStream) Buffer() []byte {
 	return js.stream.Buffer()
 }
+
+func (js *jsonStream) SetBuffer(b []byte) {
+	js.stream.SetBuffer(b)
+}
+
+func (js *jsonStream) mark() streamMark {
+	return streamMark{
+		length:    len(js.Buffer()),
+		attrs:     js.attrs,
+		needComma: js.needComma,
+	}
+}
+
+// rollback discards everything written since m, counting the attributes it removes as dropped.
+func (js *jsonStream) rollback(m streamMark) {
+	js.SetBuffer(js.Buffer()[:m.length])
+	js.forgetFields(m.length)
+	js.dropped += js.attrs - m.attrs
+	js.attrs = m.attrs
+	js.needComma = m.needComma
+}
+
+// limitValue replaces the value written since m with a truncated string of its JSON encoding if it's too long.
+func (js *jsonStream) limitValue(m streamMark) {
+	buf := js.Buffer()
+	if js.maxValue <= 0 || len(buf)-m.length <= js.maxValue {
+		return
+	}
+	encoded := string(buf[m.length:])
+	js.SetBuffer(buf[:m.length])
+	js.WriteString(truncateString(encoded, js.maxValue))
+}
@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,6 +23,14 @@ const (
 // PidKey is the reserved attribute key for the process ID when [Handler.Handle] does attribute-replacement.
 const PidKey = "pid-47482072-7496-40a0-a048-ccfdba4e564e"
 
+// HostKey is the reserved attribute key for the host name when [Handler.Handle] does attribute-replacement. See
+// [ShowHostname].
+const HostKey = "host-5c0e3d1e-8f0b-4d8a-9a51-2f6b7c1d9e44"
+
+// ProgramKey is the reserved attribute key for the program name when [Handler.Handle] does attribute-replacement. See
+// [ShowProgram].
+const ProgramKey = "program-b7a2e9f4-3c61-4e0d-8d2a-6f15c94b0a73"
+
 // baseHandler is a [slog.Handler] that writes log messages in the format of NetBackup legacy logs.
 type baseHandler struct {
 	destination       io.Writer
@@ -28,6 +39,60 @@ type baseHandler struct {
 	timestampFormat   string
 	useFullCallerName bool
 	numericSeverity   bool
+
+	onError  func(error)
+	fallback io.Writer
+	retries  int
+	backoff  time.Duration
+	lost     atomic.Uint64
+
+	onEncodingError func(key string, err error)
+	typeEncoders    []typeEncoder
+	precedence      []ValueMethod // nil for the default
+	standardJSON    bool
+	encoding        *valueEncoding // the encoder configuration, including the above
+
+	console      Console
+	consoleWidth int
+	consoleWrap  bool
+	color        bool     // whether console mode is in effect
+	terminal     *os.File // the destination, if it's a terminal to ask for its width
+
+	writeLock *sync.Mutex // serializes writes to the destination; nil if the destination handles concurrency itself
+
+	maxMessageLength int
+	maxValueLength   int
+	maxAttrs         int
+	maxDepth         int
+	maxLineLength    int
+
+	duplicates Duplicates
+
+	override atomic.Pointer[levelOverride] // set through LevelHandler
+	vmodule  atomic.Pointer[vmodule]       // set with VModule, SetVModule, or LevelHandler
+
+	flightSize int
+	flightKey  any
+	recorder   *flightRecorder // nil unless FlightRecorder is in effect
+
+	stats         handlerStats
+	statsInterval time.Duration
+
+	banner  bool
+	started time.Time
+
+	showHostname bool
+	showProgram  bool
+	hostname     string
+	program      string
+
+	location     *time.Location
+	showTimeZone bool
+	elapsed      Elapsed
+	previousTime atomic.Int64 // the time of the previous record, in Unix nanoseconds, for ElapsedSincePrevious
+
+	closeOnce sync.Once
+	closing   chan struct{} // closed by Close
 }
 
 var (
@@ -78,6 +143,24 @@ func UseFullCallerName(use bool) Option {
 	}
 }
 
+// ShowHostname adds the host name to the header of each line, after the timestamp: "time host [pid] <sev>". Use a
+// [ReplaceAttrFunc] that recognizes [HostKey] to substitute another name, such as a container's. The name shouldn't
+// contain spaces, or [ParseLine] won't be able to separate it from the timestamp.
+func ShowHostname(show bool) Option {
+	return func(h slog.Handler) {
+		base(h).showHostname = show
+	}
+}
+
+// ShowProgram adds the program name to the header of each line, directly before the process ID, in the manner of
+// syslog: "time prog[pid] <sev>". The default name is the base name of the executable; use a [ReplaceAttrFunc] that
+// recognizes [ProgramKey] to substitute another. The name shouldn't contain spaces.
+func ShowProgram(show bool) Option {
+	return func(h slog.Handler) {
+		base(h).showProgram = show
+	}
+}
+
 // NumericSeverity configures the handler to record the log level as a number instead of a text label. Numbers used
 // correspond to NetBackup severity levels, not [slog] levels:
 //
@@ -105,12 +188,15 @@ type legacyHandler interface {
 
 	groups() []string
 
+	// Stats returns the statistics of the base handler of the chain.
+	Stats() Stats
+
 	// writeWithContinuation will write the handler's portion of the log message. This method gets called recursively by
 	// the child handlers along a chain of handlers. When the recursion reaches the base case, it uses writeNested to
 	// render the "inner" portion of the log message represented by the child handlers. When the callback finally
 	// returns to the recursion's base case, then it writes any necessary closing braces before finally copying the
 	// rendered log message to the output stream.
-	writeWithContinuation(out *jsonStream, record slog.Record, writeNested nestedCallback) error
+	writeWithContinuation(ctx context.Context, out *jsonStream, record slog.Record, writeNested nestedCallback) error
 }
 
 // groupHandler is a child handler to represent the result of calling WithGroup.
@@ -141,11 +227,13 @@ var (
 // message. It will synthesize attributes representing the timestamp, process ID, level, and message, giving the program
 // an opportunity to modify, replace, or remove any of them, just as for any other attributes. Such synthetic attributes
 // are identified with the labels [slog.TimeKey], [PidKey], [slog.LevelKey], and [slog.MessageKey], respectively, each
-// with an empty group array.
+// with an empty group array. When enabled, the host name and program name are synthesized the same way, with the labels
+// [HostKey] and [ProgramKey].
 //
 // If the replacement callback for the [slog.TimeKey] attribute returns a [time.Time] value, then it will be formatted
 // with the configured [TimestampFormat] option.
 func New(w io.Writer, opts ...Option) slog.Handler {
+	hostname, _ := os.Hostname()
 	handler := &baseHandler{
 		destination: w,
 
@@ -154,17 +242,71 @@ func New(w io.Writer, opts ...Option) slog.Handler {
 		timestampFormat:   FullDateFormat,
 		useFullCallerName: false,
 		numericSeverity:   false,
+
+		onError:  nil,
+		fallback: nil,
+		retries:  0,
+		backoff:  0,
+
+		onEncodingError: nil,
+		typeEncoders:    nil,
+		precedence:      nil,
+		standardJSON:    false,
+		encoding:        nil,
+
+		console:      ConsoleOff,
+		consoleWidth: 0,
+		consoleWrap:  false,
+
+		writeLock: nil,
+
+		maxMessageLength: 0,
+		maxValueLength:   0,
+		maxAttrs:         0,
+		maxDepth:         0,
+		maxLineLength:    0,
+
+		duplicates: DuplicatesKeepAll,
+
+		flightSize: 0,
+		flightKey:  nil,
+		recorder:   nil,
+
+		statsInterval: 0,
+
+		banner:  false,
+		started: time.Now(),
+
+		showHostname: false,
+		showProgram:  false,
+		hostname:     hostname,
+		program:      filepath.Base(os.Args[0]),
+
+		location:     nil,
+		showTimeZone: false,
+		elapsed:      ElapsedOff,
+
+		closing: make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(handler)
 	}
+	handler.setUpConsole()
+	handler.setUpFlightRecorder()
+	handler.setUpEncoding()
+	handler.startStatsSummary()
+	handler.writeBanner()
 
 	return handler
 }
 
 // Enabled implements [slog.Handler.Enabled].
 func (h *baseHandler) Enabled(_ context.Context, alev slog.Level) bool {
-	return alev >= h.level.Level()
+	if alev < h.minimumLevel() && !h.vmoduleAdmits(alev) && h.recorder == nil {
+		h.stats.filtered.Add(1)
+		return false
+	}
+	return true
 }
 
 // Enabled implements [slog.Handler.Enabled].
@@ -237,19 +379,46 @@ func writeTimestamp(out *jsonStream, h *baseHandler, rec slog.Record) {
 	}
 	var timestamp string
 	if timeAttr.Value.Kind() == slog.KindTime {
-		timestamp = timeAttr.Value.Time().Format(h.timestampFormat)
+		timestamp = h.formatTime(timeAttr.Value.Time())
 	} else {
 		timestamp = timeAttr.Value.String()
 	}
-	out.WriteRaw(timestamp + " ")
+	out.WriteRaw(h.paint(styleDim, timestamp) + " ")
 }
 
+func writeHost(out *jsonStream, h *baseHandler, _ slog.Record) {
+	if !h.showHostname {
+		return
+	}
+	hostAttr := h.replaceAttrs([]string{}, slog.String(HostKey, h.hostname))
+	if hostAttr.Equal(slog.Attr{}) {
+		return
+	}
+	out.WriteRaw(h.paint(styleDim, hostAttr.Value.String()) + " ")
+}
+
+// writePid writes the process ID in brackets, preceded directly by the program name if that's enabled.
 func writePid(out *jsonStream, h *baseHandler, _ slog.Record) {
+	header := h.programName()
 	pidAttr := h.replaceAttrs([]string{}, slog.Int(PidKey, os.Getpid()))
-	if pidAttr.Equal(slog.Attr{}) {
+	if !pidAttr.Equal(slog.Attr{}) {
+		header += "[" + pidAttr.Value.String() + "]"
+	}
+	if header == "" {
 		return
 	}
-	out.WriteRaw("[" + pidAttr.Value.String() + "] ")
+	out.WriteRaw(h.paint(styleDim, header) + " ")
+}
+
+func (h *baseHandler) programName() string {
+	if !h.showProgram {
+		return ""
+	}
+	programAttr := h.replaceAttrs([]string{}, slog.String(ProgramKey, h.program))
+	if programAttr.Equal(slog.Attr{}) {
+		return ""
+	}
+	return programAttr.Value.String()
 }
 
 func scaleLevel(leveler slog.Leveler) float64 {
@@ -258,6 +427,13 @@ func scaleLevel(leveler slog.Leveler) float64 {
 	return math.Pow(2, float64(leveler.Level())/diff+offset) //revive:disable-line:add-constant
 }
 
+// unscaleLevel is the inverse of scaleLevel. It converts a NetBackup severity number back into a [slog.Level].
+func unscaleLevel(severity float64) slog.Level {
+	diff := float64(slog.LevelError - slog.LevelWarn)
+	offset := 1 - float64(slog.LevelDebug)/diff
+	return slog.Level(math.Round((math.Log2(severity) - offset) * diff))
+}
+
 func writeLevel(out *jsonStream, h *baseHandler, rec slog.Record) {
 	levelAttr := h.replaceAttrs([]string{}, slog.Any(slog.LevelKey, rec.Level))
 	if levelAttr.Equal(slog.Attr{}) {
@@ -270,7 +446,7 @@ func writeLevel(out *jsonStream, h *baseHandler, rec slog.Record) {
 			levelAttr.Value = slog.Float64Value(newLevel)
 		}
 	}
-	out.WriteRaw("<" + levelAttr.Value.String() + "> ")
+	out.WriteRaw(h.paint(levelStyle(rec.Level), "<"+levelAttr.Value.String()+">") + " ")
 }
 
 func writeCaller(out *jsonStream, h *baseHandler, rec slog.Record) {
@@ -295,14 +471,28 @@ func writeMessage(out *jsonStream, h *baseHandler, rec slog.Record) {
 	if msgAttr.Equal(slog.Attr{}) {
 		return
 	}
-	out.WriteRaw(msgAttr.Value.String())
+	out.WriteRaw(h.limitMessage(msgAttr.Value.String(), len(out.Buffer())))
 }
 
+// writeNextAttribute writes an attribute, subject to the size limits. Attributes that exceed the limits are dropped and
+// counted so that writeAttributes can note them.
 func (h *baseHandler) writeNextAttribute(a slog.Attr, out *jsonStream, groups []string) bool {
 	a = h.replaceAttrs(groups, a)
-	if !a.Equal(slog.Attr{}) {
-		writeAttribute(out, h, groups, a)
+	if a.Equal(slog.Attr{}) {
+		return true
+	}
+	a.Value = h.resolve(a.Value)
+	out.collect(groups, a)
+	if out.full || h.attrLimitReached(out, a) {
+		out.dropped += countAttrs(a)
+		return true
 	}
+	m := out.mark()
+	writeAttribute(out, h, groups, a)
+	if a.Value.Kind() != slog.KindGroup {
+		out.attrs++
+	}
+	h.enforceLineLimit(out, m)
 	return true
 }
 
@@ -337,11 +527,20 @@ func writeAttributes(writeNested nestedCallback) writingStepFunc {
 		if writeNested == nil {
 			return
 		}
+		start := len(out.Buffer())
+		out.keyStyle = base.keyStyle()
+		out.maxValue = base.maxValueLength
+		out.duplicates = base.duplicates
+		out.onEncodingError = base.onEncodingError
+		_, out.collecting = base.destination.(RecordWriter)
 		out.WriteRaw(" ")
 		out.WriteObjectStart()
-		for range 1 + writeNested(base, out) {
+		for range writeNested(base, out) {
 			out.WriteObjectEnd()
 		}
+		writeDroppedAttrs(out)
+		out.WriteObjectEnd()
+		base.fitTail(out, start)
 	}
 }
 
@@ -349,12 +548,41 @@ func writeEnd(out *jsonStream, _ *baseHandler, _ slog.Record) {
 	out.WriteRaw("\n")
 }
 
-// writeWithContinuation renders the entire log message. Groups and attributes from child handlers are written by the
-// writeNested callback function. This function writes all the other log information prior to writing the nested
-// attributes.
-func (h *baseHandler) writeWithContinuation(out *jsonStream, record slog.Record, writeNested nestedCallback) error {
+// writeWithContinuation renders the entire log message and writes it to the destination, or keeps it in the flight
+// recorder if it's below the handler's level. Groups and attributes from child handlers are written by the writeNested
+// callback function.
+func (h *baseHandler) writeWithContinuation(
+	ctx context.Context, out *jsonStream, record slog.Record, writeNested nestedCallback,
+) error {
+	if !h.admits(record) {
+		if h.recorder != nil {
+			return h.recorder.keep(ctx, h, out, record, writeNested)
+		}
+		h.stats.filtered.Add(1)
+		return nil
+	}
+	if h.recorder != nil && record.Level >= slog.LevelError {
+		h.recorder.replay(ctx, h)
+	}
+	return h.output(out, record, writeNested)
+}
+
+// output renders the entire log message and writes it to the destination, regardless of its level.
+func (h *baseHandler) output(out *jsonStream, record slog.Record, writeNested nestedCallback) error {
+	start := time.Now()
+	defer func() { h.stats.handledIn(time.Since(start)) }()
+	if err := h.render(out, record, writeNested); err != nil {
+		return err
+	}
+	return h.emit(out.Buffer(), recordInfo(out, record))
+}
+
+// render writes the entire log message to out. Groups and attributes from child handlers are written by the writeNested
+// callback function. This function writes all the other log information prior to writing the nested attributes.
+func (h *baseHandler) render(out *jsonStream, record slog.Record, writeNested nestedCallback) error {
 	for _, writer := range []writingStepFunc{
 		writeTimestamp,
+		writeHost,
 		writePid,
 		writeLevel,
 		writeCaller,
@@ -367,17 +595,14 @@ func (h *baseHandler) writeWithContinuation(out *jsonStream, record slog.Record,
 			return out.Error()
 		}
 	}
-
-	if out.Error() != nil {
-		return out.Error()
-	}
-	_, err := h.destination.Write(out.Buffer())
-	return err
+	return nil
 }
 
 // writeWithContinuation generates a callback that will begin a JSON object for the handler's group when called by the
 // parent log handler.
-func (h *groupHandler) writeWithContinuation(out *jsonStream, record slog.Record, writeNested nestedCallback) error {
+func (h *groupHandler) writeWithContinuation(
+	ctx context.Context, out *jsonStream, record slog.Record, writeNested nestedCallback,
+) error {
 	var newWriteAttributes nestedCallback
 	if writeNested != nil {
 		// The child handler has attributes to write, so our group counts.
@@ -387,12 +612,14 @@ func (h *groupHandler) writeWithContinuation(out *jsonStream, record slog.Record
 			return 1 + writeNested(base, out)
 		}
 	}
-	return h.previousHandler.writeWithContinuation(out, record, newWriteAttributes)
+	return h.previousHandler.writeWithContinuation(ctx, out, record, newWriteAttributes)
 }
 
 // writeWithContinuation generates a callback that will write the current handler's accumulated attributes when called
 // by the parent log handler.
-func (h *attrHandler) writeWithContinuation(out *jsonStream, record slog.Record, writeNested nestedCallback) error {
+func (h *attrHandler) writeWithContinuation(
+	ctx context.Context, out *jsonStream, record slog.Record, writeNested nestedCallback,
+) error {
 	newWriteAttributes := func(base *baseHandler, out *jsonStream) uint {
 		for _, attr := range h.attributes {
 			_ = base.writeNextAttribute(attr, out, h.groups())
@@ -402,34 +629,40 @@ func (h *attrHandler) writeWithContinuation(out *jsonStream, record slog.Record,
 		}
 		return 0
 	}
-	return h.previousHandler.writeWithContinuation(out, record, newWriteAttributes)
+	return h.previousHandler.writeWithContinuation(ctx, out, record, newWriteAttributes)
 }
 
-func commonHandle(h legacyHandler, record slog.Record) error {
-	out := newJSONStream()
-	var writeAttributes nestedCallback
-	if record.NumAttrs() != 0 {
-		writeAttributes = func(base *baseHandler, out *jsonStream) uint {
-			record.Attrs(func(a slog.Attr) bool {
-				return base.writeNextAttribute(a, out, h.groups())
-			})
-			return 0
-		}
+// recordAttributes generates the innermost callback of the chain, which writes the record's own attributes. It returns
+// nil if the record has no attributes.
+func recordAttributes(h legacyHandler, record slog.Record) nestedCallback {
+	if record.NumAttrs() == 0 {
+		return nil
 	}
-	return h.writeWithContinuation(out, record, writeAttributes)
+	return func(base *baseHandler, out *jsonStream) uint {
+		record.Attrs(func(a slog.Attr) bool {
+			return base.writeNextAttribute(a, out, h.groups())
+		})
+		return 0
+	}
+}
+
+func commonHandle(ctx context.Context, h legacyHandler, record slog.Record) error {
+	base, _ := rootHandler(h)
+	out := newJSONStream(base.encoding)
+	return h.writeWithContinuation(ctx, out, record, recordAttributes(h, record))
 }
 
 // Handle implements [slog.Handler.Handle].
-func (h *baseHandler) Handle(_ context.Context, record slog.Record) error {
-	return commonHandle(h, record)
+func (h *baseHandler) Handle(ctx context.Context, record slog.Record) error {
+	return commonHandle(ctx, h, record)
 }
 
 // Handle implements [slog.Handler.Handle].
-func (h *groupHandler) Handle(_ context.Context, record slog.Record) error {
-	return commonHandle(h, record)
+func (h *groupHandler) Handle(ctx context.Context, record slog.Record) error {
+	return commonHandle(ctx, h, record)
 }
 
 // Handle implements [slog.Handler.Handle].
-func (h *attrHandler) Handle(_ context.Context, record slog.Record) error {
-	return commonHandle(h, record)
+func (h *attrHandler) Handle(ctx context.Context, record slog.Record) error {
+	return commonHandle(ctx, h, record)
 }
@@ -0,0 +1,248 @@
+package nblog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// levelOverride is a level set at run time through [LevelHandler], replacing the level from the [Level] option.
+type levelOverride struct {
+	level   slog.Level
+	expires time.Time // zero if the override doesn't expire
+	timer   *time.Timer
+}
+
+// minimumLevel returns the level below which records are discarded, taking any override into account.
+func (h *baseHandler) minimumLevel() slog.Level {
+	if o := h.override.Load(); o != nil {
+		return o.level
+	}
+	return h.level.Level()
+}
+
+// setOverride replaces the handler's level until ttl elapses, or indefinitely if ttl is zero or less. Setting nil
+// removes the override. Callers must not change the override concurrently.
+func (h *baseHandler) setOverride(o *levelOverride, ttl time.Duration) {
+	if o != nil && ttl > 0 {
+		o.expires = time.Now().Add(ttl)
+	}
+	if old := h.override.Swap(o); old != nil && old.timer != nil {
+		old.timer.Stop()
+	}
+	if o != nil && ttl > 0 {
+		o.timer = time.AfterFunc(ttl, func() {
+			if h.override.CompareAndSwap(o, nil) {
+				h.note("log level override expired", slog.String("from", o.level.String()),
+					slog.String("to", h.level.Level().String()))
+			}
+		})
+	}
+}
+
+// note logs a record about the handler itself, such as a change to its configuration. It's written regardless of the
+// handler's level.
+func (h *baseHandler) note(msg string, attrs ...slog.Attr) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)
+	r.AddAttrs(attrs...)
+	_ = h.output(newJSONStream(h.encoding), r, recordAttributes(h, r))
+}
+
+// rootHandler finds the handler created by [New] at the base of a chain of derived handlers.
+func rootHandler(h slog.Handler) (*baseHandler, bool) {
+	for {
+		switch t := h.(type) {
+		case *baseHandler:
+			return t, true
+		case *groupHandler:
+			h = t.previousHandler
+		case *attrHandler:
+			h = t.previousHandler
+		default:
+			return nil, false
+		}
+	}
+}
+
+// levelServer is the [http.Handler] returned by [LevelHandler].
+type levelServer struct {
+	base *baseHandler
+	mu   sync.Mutex // serializes changes
+}
+
+// levelState is the JSON representation of a handler's levels.
+type levelState struct {
+	Level      string `json:"level"`
+	Configured string `json:"configured"`
+	Expires    string `json:"expires,omitempty"`
+	VModule    string `json:"vmodule,omitempty"`
+}
+
+// levelChange is the JSON form of a request to change the level.
+type levelChange struct {
+	Level   string  `json:"level"`
+	TTL     string  `json:"ttl"`
+	VModule *string `json:"vmodule"` // nil if the request leaves the per-function levels alone
+}
+
+// LevelHandler returns an HTTP handler for viewing and changing the level of h, which must have been created by [New]
+// or derived from such a handler. The new level affects the original handler and every handler derived from it.
+//
+// A GET request returns the current state as a JSON object: "level" is the level in effect, "configured" is the level
+// from the [Level] option, and "expires" is when a temporary change will revert, if one is in effect.
+//
+// A PUT or POST request changes the level. It takes the parameters "level" and, optionally, "ttl", either as form
+// values or as a JSON object. The level can be a [slog.Level] name such as "DEBUG" or "WARN+2", or a NetBackup
+// severity number as described for [NumericSeverity]. The TTL is a duration such as "15m"; when it elapses, the
+// handler reverts to its configured level. A DELETE request reverts to the configured level immediately.
+//
+// The state also includes "vmodule", the per-function levels set with [VModule], and a change request can replace them
+// with a "vmodule" parameter, in place of or in addition to "level". A TTL doesn't apply to them, and DELETE leaves
+// them alone; set an empty "vmodule" to remove them.
+//
+// Each change, and each automatic reversion, is logged as a record at [slog.LevelInfo] through h, whatever its level.
+func LevelHandler(h slog.Handler) http.Handler {
+	base, ok := rootHandler(h)
+	if !ok {
+		panic("LevelHandler applied to wrong type")
+	}
+	return &levelServer{base: base}
+}
+
+// ServeHTTP implements [http.Handler.ServeHTTP].
+func (s *levelServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		if err := s.change(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		s.revert(r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.state())
+}
+
+func (s *levelServer) state() levelState {
+	state := levelState{
+		Level:      s.base.minimumLevel().String(),
+		Configured: s.base.level.Level().String(),
+		VModule:    s.base.vmoduleSpec(),
+	}
+	if o := s.base.override.Load(); o != nil && !o.expires.IsZero() {
+		state.Expires = o.expires.Format(time.RFC3339)
+	}
+	return state
+}
+
+func (s *levelServer) change(r *http.Request) error {
+	req, err := readLevelChange(r)
+	if err != nil {
+		return err
+	}
+	var vm *vmodule
+	if req.VModule != nil {
+		if vm, err = parseVModule(*req.VModule); err != nil {
+			return err
+		}
+	}
+	if req.Level == "" {
+		s.changeVModule(vm, r.RemoteAddr)
+		return nil
+	}
+	level, err := parseLevelSetting(req.Level)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return fmt.Errorf("invalid ttl: %w", err)
+		}
+	}
+	if req.VModule != nil {
+		s.changeVModule(vm, r.RemoteAddr)
+	}
+	s.changeLevel(level, ttl, r.RemoteAddr)
+	return nil
+}
+
+func (s *levelServer) changeLevel(level slog.Level, ttl time.Duration, remote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := s.base.minimumLevel()
+	s.base.setOverride(&levelOverride{level: level}, ttl)
+	attrs := []slog.Attr{slog.String("from", from.String()), slog.String("to", level.String())}
+	if ttl > 0 {
+		attrs = append(attrs, slog.Duration("ttl", ttl))
+	}
+	s.base.note("log level changed", append(attrs, slog.String("remote", remote))...)
+}
+
+func (s *levelServer) changeVModule(vm *vmodule, remote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := s.base.vmoduleSpec()
+	s.base.vmodule.Store(vm)
+	s.base.note("log vmodule changed", slog.String("from", from), slog.String("to", s.base.vmoduleSpec()),
+		slog.String("remote", remote))
+}
+
+func (s *levelServer) revert(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := s.base.minimumLevel()
+	s.base.setOverride(nil, 0)
+	s.base.note("log level reset", slog.String("from", from.String()),
+		slog.String("to", s.base.level.Level().String()), slog.String("remote", r.RemoteAddr))
+}
+
+// readLevelChange gets the parameters of a change request from a JSON body or from form values.
+func readLevelChange(r *http.Request) (levelChange, error) {
+	var req levelChange
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+	} else {
+		req.Level = r.FormValue("level")
+		req.TTL = r.FormValue("ttl")
+		if spec, ok := r.Form["vmodule"]; ok {
+			req.VModule = &spec[0]
+		}
+	}
+	if req.Level == "" && req.VModule == nil {
+		return req, errors.New("missing level")
+	}
+	return req, nil
+}
+
+// parseLevelSetting accepts a level name as understood by [slog.Level.UnmarshalText] or a NetBackup severity number.
+// Unlike parseLevel, it rejects anything else.
+func parseLevelSetting(s string) (slog.Level, error) {
+	if severity, err := strconv.ParseFloat(s, 64); err == nil {
+		if severity <= 0 {
+			return 0, fmt.Errorf("invalid severity %q", s)
+		}
+		return unscaleLevel(severity), nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, err
+	}
+	return level, nil
+}
@@ -0,0 +1,120 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// SyncLineBuffer is a [LineBuffer] that's safe to read while records are being written from other goroutines.
+type SyncLineBuffer struct {
+	mu    sync.Mutex
+	lines LineBuffer
+}
+
+func (sb *SyncLineBuffer) Write(b []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.lines.Write(b)
+}
+
+func (sb *SyncLineBuffer) Lines() []string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return append([]string(nil), sb.lines.Lines...)
+}
+
+func getLevels(g *WithT, server http.Handler) map[string]string {
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	var state map[string]string
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &state)).To(Succeed())
+	return state
+}
+
+func putLevel(server http.Handler, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLevelHandler(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	h := nblog.New(output, nblog.Level(slog.LevelWarn))
+	logger := slog.New(h).With("component", "catalog")
+	server := nblog.LevelHandler(logger.Handler())
+
+	g.Expect(getLevels(g, server)).To(Equal(map[string]string{"level": "WARN", "configured": "WARN"}))
+	logger.Debug("hidden")
+
+	rec := putLevel(server, url.Values{"level": {"DEBUG"}})
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(getLevels(g, server)).To(HaveKeyWithValue("level", "DEBUG"))
+	logger.Debug("shown")
+
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	g.Expect(getLevels(g, server)).To(HaveKeyWithValue("level", "WARN"))
+	logger.Debug("hidden again")
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		MatchRegexp(`<INFO> log level changed \{"from": "WARN", "to": "DEBUG", "remote": ".*"\}$`),
+		HaveSuffix(`: shown {"component": "catalog"}`),
+		MatchRegexp(`<INFO> log level reset \{"from": "DEBUG", "to": "WARN", "remote": ".*"\}$`),
+	))
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	h := nblog.New(output)
+	server := nblog.LevelHandler(h)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"level": "2", "ttl": "50ms"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(getLevels(g, server)).To(And(HaveKeyWithValue("level", "DEBUG"), HaveKey("expires")))
+	g.Expect(h.Enabled(t.Context(), slog.LevelDebug)).To(BeTrue())
+
+	g.Eventually(func() bool { return h.Enabled(t.Context(), slog.LevelDebug) }, time.Second).Should(BeFalse())
+	g.Eventually(output.Lines).Should(HaveExactElements(
+		MatchRegexp(`<INFO> log level changed \{"from": "INFO", "to": "DEBUG", "ttl": "50ms", "remote": ".*"\}$`),
+		HaveSuffix(`<INFO> log level override expired {"from": "DEBUG", "to": "INFO"}`),
+	))
+}
+
+func TestLevelHandlerErrors(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	server := nblog.LevelHandler(nblog.New(&LineBuffer{}))
+
+	g.Expect(putLevel(server, url.Values{}).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(putLevel(server, url.Values{"level": {"LOUD"}}).Code).To(Equal(http.StatusBadRequest))
+	g.Expect(putLevel(server, url.Values{"level": {"INFO"}, "ttl": {"soon"}}).Code).To(Equal(http.StatusBadRequest))
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	g.Expect(getLevels(g, server)).To(HaveKeyWithValue("level", "INFO"))
+}
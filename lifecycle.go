@@ -0,0 +1,102 @@
+package nblog
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// flusher is implemented by destinations that buffer their output, such as [File].
+type flusher interface {
+	Flush() error
+}
+
+// Flush writes out any output that the destination of h has buffered, if the destination has a Flush method like that
+// of [File]. The handler must have been created by [New] or derived from such a handler.
+func Flush(h slog.Handler) error {
+	b, ok := rootHandler(h)
+	if !ok {
+		panic("Flush applied to wrong type")
+	}
+	return b.flush()
+}
+
+// Close shuts down h: it logs the shutdown record if the handler was created with [Banner], stops the periodic
+// summary requested with [StatsInterval], flushes the destination, and closes the destination if this package created
+// it: a [File], [RotatingFile], [SyslogWriter], or [JournalWriter]. Other destinations, such as [os.Stderr] or a file
+// the caller opened, belong to the caller and are left open. Records logged through h afterward are handed to the
+// destination, which will typically fail to write them if it was closed. The handler must have been created by [New]
+// or derived from such a handler.
+func Close(h slog.Handler) error {
+	b, ok := rootHandler(h)
+	if !ok {
+		panic("Close applied to wrong type")
+	}
+	return b.close("closed")
+}
+
+// CloseOnSignal arranges for h to be closed, as by [Close], when the process receives an interrupt or termination
+// signal (SIGINT or SIGTERM). After closing h, it restores the default handling of the signal and delivers the signal
+// again, so the process terminates as it would have without nblog. If that isn't possible on the platform, the process
+// exits with status 1. The returned function cancels the arrangement.
+func CloseOnSignal(h slog.Handler) (stop func()) {
+	b, ok := rootHandler(h)
+	if !ok {
+		panic("CloseOnSignal applied to wrong type")
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			_ = b.close("signal: " + sig.String())
+			signal.Stop(signals)
+			redeliver(sig)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// redeliver sends sig to the current process now that it will get the default handling.
+func redeliver(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func (h *baseHandler) flush() error {
+	if f, ok := h.destination.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// close writes the shutdown record, with the given reason, and then flushes the destination and closes it if this
+// package created it. Only the first call does anything.
+func (h *baseHandler) close(reason string) error {
+	err := os.ErrClosed
+	h.closeOnce.Do(func() {
+		if h.banner {
+			h.shutdown(reason)
+		}
+		close(h.closing)
+		err = h.flush()
+		switch d := h.destination.(type) {
+		case *File, *RotatingFile, *SyslogWriter, *JournalWriter:
+			err = errors.Join(err, d.(io.Closer).Close()) //revive:disable-line:unchecked-type-assertion
+		}
+	})
+	return err
+}
@@ -0,0 +1,148 @@
+package nblog
+
+import (
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+)
+
+// Room to leave for truncation markers when enforcing [MaxLineLength]. The counts are assumed to need at most ten
+// digits.
+const (
+	bytesMarkerReserve = len(ellipsis + "(truncated 9999999999 bytes)")
+	attrsMarkerReserve = len(`, "` + ellipsis + `": "(truncated 9999999999 attributes)"`)
+)
+
+// MaxMessageLength limits the length of the log message to n bytes. Longer messages are cut at a character boundary and
+// marked with the number of bytes removed, as in "…(truncated 48213 bytes)". Zero or less means there is no limit,
+// which is the default.
+func MaxMessageLength(n int) Option {
+	return func(h slog.Handler) {
+		base(h).maxMessageLength = n
+	}
+}
+
+// MaxValueLength limits the length of each attribute value to n bytes. Strings that are too long are cut and marked
+// the same way as messages (see [MaxMessageLength]). Other values whose JSON encoding is too long, such as big structs
+// or maps, are replaced by a string holding the beginning of their encoding, followed by the marker. Zero or less means
+// there is no limit, which is the default.
+func MaxValueLength(n int) Option {
+	return func(h slog.Handler) {
+		base(h).maxValueLength = n
+	}
+}
+
+// MaxAttrs limits the number of attributes in a log line to n, not counting groups themselves. Attributes beyond the
+// limit are dropped, and the end of the attribute tail gets an extra attribute noting how many, as in
+// "…": "(truncated 12 attributes)". Zero or less means there is no limit, which is the default.
+func MaxAttrs(n int) Option {
+	return func(h slog.Handler) {
+		base(h).maxAttrs = n
+	}
+}
+
+// MaxDepth limits how deeply groups can nest in the attribute tail. A group attribute that would be nested more than n
+// levels deep is replaced by a string noting how many attributes it held, as in "…(truncated 3 attributes)". Groups
+// created with [slog.Handler.WithGroup] count toward the depth but are never replaced. Zero or less means there is no
+// limit, which is the default.
+func MaxDepth(n int) Option {
+	return func(h slog.Handler) {
+		base(h).maxDepth = n
+	}
+}
+
+// MaxLineLength limits the length of each log line, including the terminating newline, to n bytes. The message is
+// shortened if it doesn't fit, and then attributes are dropped, starting with the first one that doesn't fit, so that
+// the attribute tail remains valid JSON. Room is left for the markers that note what was removed, as described for
+// [MaxMessageLength] and [MaxAttrs]. A line can still exceed the limit if its timestamp, process ID, level, and caller
+// alone take more than n bytes. Zero or less means there is no limit, which is the default.
+func MaxLineLength(n int) Option {
+	return func(h slog.Handler) {
+		base(h).maxLineLength = n
+	}
+}
+
+// truncateString cuts s to at most n bytes, backing up to a character boundary, and appends a marker telling how many
+// bytes were removed.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := max(n, 0)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("%s(truncated %d bytes)", ellipsis, len(s)-cut)
+}
+
+// limitMessage applies the message and line limits to the message, given the number of bytes already used by the rest
+// of the line.
+func (h *baseHandler) limitMessage(msg string, used int) string {
+	if h.maxMessageLength > 0 {
+		msg = truncateString(msg, h.maxMessageLength)
+	}
+	if h.maxLineLength <= 0 {
+		return msg
+	}
+	room := h.maxLineLength - used - len("\n")
+	if len(msg) <= room {
+		return msg
+	}
+	// Leave room for this marker and for a marker about the attributes that can't fit either.
+	return truncateString(msg, room-bytesMarkerReserve-attrsMarkerReserve-len(" {}"))
+}
+
+// attrLimitReached reports whether the attribute should be dropped because the line already has as many attributes as
+// [MaxAttrs] allows. Groups aren't dropped here; their members are checked individually.
+func (h *baseHandler) attrLimitReached(out *jsonStream, a slog.Attr) bool {
+	return h.maxAttrs > 0 && out.attrs >= h.maxAttrs && a.Value.Kind() != slog.KindGroup
+}
+
+// enforceLineLimit rolls back the attribute written since m if it made the line too long, counting what was removed.
+// Once an attribute doesn't fit, all later ones are dropped, too.
+func (h *baseHandler) enforceLineLimit(out *jsonStream, m streamMark) {
+	if h.maxLineLength <= 0 {
+		return
+	}
+	// Account for the closing braces, the newline, and the marker for dropped attributes.
+	length := len(out.Buffer()) + out.depth + len("\n") + attrsMarkerReserve
+	if length > h.maxLineLength {
+		out.rollback(m)
+		out.full = true
+	}
+}
+
+// depthLimitReached reports whether a group at the stream's current position would exceed [MaxDepth].
+func (h *baseHandler) depthLimitReached(out *jsonStream) bool {
+	// The attribute tail's own braces make the first level.
+	return h.maxDepth > 0 && out.depth > h.maxDepth
+}
+
+// countAttrs counts the non-group attributes in a, including a itself if it's not a group.
+func countAttrs(a slog.Attr) int {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return 1
+	}
+	count := 0
+	for _, member := range v.Group() {
+		count += countAttrs(member)
+	}
+	return count
+}
+
+// writeElidedGroup writes a marker in place of a group that's nested too deeply.
+func writeElidedGroup(out *jsonStream, attr slog.Attr) {
+	out.WriteObjectField(attr.Key)
+	out.WriteString(fmt.Sprintf("%s(truncated %d attributes)", ellipsis, countAttrs(attr)))
+	out.attrs++
+}
+
+// writeDroppedAttrs writes the attribute that notes how many attributes were dropped, if any.
+func writeDroppedAttrs(out *jsonStream) {
+	if out.dropped == 0 {
+		return
+	}
+	out.WriteObjectField(ellipsis)
+	out.WriteString(fmt.Sprintf("(truncated %d attributes)", out.dropped))
+}
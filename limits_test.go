@@ -0,0 +1,105 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestMaxMessageLength(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.MaxMessageLength(10)))
+
+	logger.Info("short")
+	logger.Info(strings.Repeat("é", 20))
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		HaveSuffix(": short"),
+		HaveSuffix(": ééééé…(truncated 30 bytes)"),
+	))
+}
+
+func TestMaxValueLength(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.MaxValueLength(8)))
+
+	logger.Info("message",
+		slog.String("short", "abc"),
+		slog.String("long", strings.Repeat("x", 20)),
+		slog.Any("struct", struct{ Alpha, Beta int }{1, 2}),
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(
+		`: message {"short": "abc", "long": "xxxxxxxx…(truncated 12 bytes)", ` +
+			`"struct": "{\"Alpha\"…(truncated 12 bytes)"}`,
+	)))
+	entry, err := nblog.ParseLine(output.Lines[0])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entry.Attrs).To(HaveLen(3))
+}
+
+func TestMaxAttrs(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.MaxAttrs(3))).With("a", 1).WithGroup("g")
+
+	logger.Info("message", "b", 2, slog.Group("h", "c", 3, "d", 4), "e", 5)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(
+		`: message {"a": 1, "g": {"b": 2, "h": {"c": 3}}, "…": "(truncated 2 attributes)"}`,
+	)))
+}
+
+func TestMaxDepth(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.MaxDepth(2)))
+
+	logger.Info("message", slog.Group("one", slog.Group("two", slog.Group("three", "a", 1, "b", 2))))
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(
+		`: message {"one": {"two": {"three": "…(truncated 2 attributes)"}}}`,
+	)))
+}
+
+func TestMaxLineLength(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	const limit = 200
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.MaxLineLength(limit)))
+
+	padding := strings.Repeat("x", 40)
+	logger.Info("attributes",
+		slog.Group("g", "first", padding, "second", padding),
+		"third", padding,
+		"fourth", 4,
+	)
+	logger.Info(strings.Repeat("m", 500), "attr", 1)
+
+	g.Expect(output.Lines).To(HaveLen(2))
+	g.Expect(output.Lines[0]).To(HaveSuffix(
+		`: attributes {"g": {"first": "` + padding + `"}, "…": "(truncated 3 attributes)"}`,
+	))
+	g.Expect(output.Lines[1]).To(MatchRegexp(`: m+…\(truncated \d+ bytes\) \{"…": "\(truncated 1 attributes\)"\}$`))
+	for _, line := range output.Lines {
+		g.Expect(len(line) + 1).To(BeNumerically("<=", limit))
+		_, err := nblog.ParseLine(line)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+}
@@ -0,0 +1,351 @@
+package nblog
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"unsafe"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/modern-go/reflect2"
+)
+
+// ValueMethod identifies a method by which an attribute value can describe how it should be written. See
+// [MarshalPrecedence].
+type ValueMethod int
+
+// These are the methods for use with [MarshalPrecedence].
+const (
+	// MethodLogValuer writes the result of the LogValue method of [slog.LogValuer], with groups as JSON objects.
+	MethodLogValuer ValueMethod = iota
+	// MethodJSONMarshaler writes the result of the MarshalJSON method of [json.Marshaler].
+	MethodJSONMarshaler
+	// MethodTextMarshaler writes the result of the MarshalText method of [encoding.TextMarshaler] as a string.
+	MethodTextMarshaler
+	// MethodStringer writes the result of the String method of [fmt.Stringer] as a string.
+	MethodStringer
+)
+
+// defaultPrecedence is the order in which the handler tries a value's methods unless [MarshalPrecedence] says
+// otherwise. It leaves out [MethodStringer], so that structs with a String method for other purposes keep their fields.
+var defaultPrecedence = []ValueMethod{MethodLogValuer, MethodJSONMarshaler, MethodTextMarshaler}
+
+// methodInterfaces holds the interface that declares each ValueMethod.
+var methodInterfaces = [...]reflect.Type{
+	MethodLogValuer:     reflect.TypeFor[slog.LogValuer](),
+	MethodJSONMarshaler: reflect.TypeFor[json.Marshaler](),
+	MethodTextMarshaler: reflect.TypeFor[encoding.TextMarshaler](),
+	MethodStringer:      reflect.TypeFor[fmt.Stringer](),
+}
+
+// MarshalPrecedence sets the order in which the handler looks for methods on an attribute value, or on a value nested
+// within one, to decide how to write it. The first listed method that the value's type implements is used. Methods
+// that aren't listed are ignored, and a value that implements none of the listed methods is written by reflecting on
+// its fields or elements, like any other value. Only methods in the type's own method set count, so a method with a
+// pointer receiver applies to pointers but not to values.
+//
+// The default order is [MethodLogValuer], [MethodJSONMarshaler], [MethodTextMarshaler]. String methods are ignored
+// unless [MethodStringer] is listed, since many types define one for display rather than for logging; a struct with
+// one is written with its fields. With no methods at all, every value is written by reflection, apart from the types
+// that have encoders; see [EncodeType].
+func MarshalPrecedence(methods ...ValueMethod) Option {
+	return func(h slog.Handler) {
+		base(h).precedence = append([]ValueMethod{}, methods...)
+	}
+}
+
+// StandardJSON makes the handler encode attribute values the way [encoding/json] does, within the limits of
+// jsoniter's compatible configuration: map keys are sorted, so maps are written in a stable order, and the results of
+// MarshalJSON methods are validated. Unlike [encoding/json], the handler never escapes HTML characters. It's off by
+// default, which writes maps in random order.
+func StandardJSON(enable bool) Option {
+	return func(h slog.Handler) {
+		base(h).standardJSON = enable
+	}
+}
+
+// valueEncoding is a handler's encoder configuration: a jsoniter configuration that applies the registered encoders
+// and the method precedence, decided once for each type.
+type valueEncoding struct {
+	jsoniter.DummyExtension
+	api        jsoniter.API
+	encoders   []typeEncoder
+	precedence []ValueMethod
+	choices    sync.Map // reflect.Type to valueChoice
+}
+
+// defaultEncoding is the encoder configuration for handlers with the default options.
+var defaultEncoding = newValueEncoding(nil, defaultPrecedence, false)
+
+// newValueEncoding creates an encoder configuration. Values that JSON can't represent are written as strings instead
+// of making the encoder fail; see [safeEncoding].
+func newValueEncoding(encoders []typeEncoder, precedence []ValueMethod, standard bool) *valueEncoding {
+	e := &valueEncoding{encoders: encoders, precedence: precedence}
+	config := jsoniter.Config{}
+	if standard {
+		config = jsoniter.Config{SortMapKeys: true, ValidateJsonRawMessage: true}
+	}
+	e.api = config.Froze()
+	e.api.RegisterExtension(e)
+	e.api.RegisterExtension(&safeEncoding{})
+	return e
+}
+
+// choiceKind says how values of a type are written.
+type choiceKind int
+
+const (
+	chooseDefault    choiceKind = iota // as jsoniter and safeEncoding would
+	chooseEncoder                      // with a registered or built-in typeEncoder
+	chooseMethod                       // with one of the type's methods
+	chooseReflection                   // by reflection, ignoring methods that jsoniter would otherwise call
+)
+
+type valueChoice struct {
+	kind    choiceKind
+	encoder *typeEncoder
+	method  ValueMethod
+}
+
+// choose decides how values of type t are written.
+func (e *valueEncoding) choose(t reflect.Type) valueChoice {
+	if cached, ok := e.choices.Load(t); ok {
+		choice, _ := cached.(valueChoice)
+		return choice
+	}
+	choice := e.decide(t)
+	e.choices.Store(t, choice)
+	return choice
+}
+
+func (e *valueEncoding) decide(t reflect.Type) valueChoice {
+	if t.Kind() == reflect.Interface {
+		// The dynamic type decides.
+		return valueChoice{kind: chooseDefault}
+	}
+	if enc := e.lookupEncoder(t); enc != nil {
+		return valueChoice{kind: chooseEncoder, encoder: enc}
+	}
+	for _, m := range e.precedence {
+		if t.Implements(methodInterfaces[m]) {
+			return valueChoice{kind: chooseMethod, method: m}
+		}
+	}
+	if enc := lookupBuiltin(t); enc != nil {
+		return valueChoice{kind: chooseEncoder, encoder: enc}
+	}
+	if e.leavesOut(t) {
+		return valueChoice{kind: chooseReflection}
+	}
+	return valueChoice{kind: chooseDefault}
+}
+
+// leavesOut reports whether jsoniter would otherwise call a MarshalJSON or MarshalText method that doesn't apply to
+// values of t: one that the precedence doesn't list, or one with a pointer receiver, which jsoniter calls on
+// addressable values such as struct fields and slice elements.
+func (e *valueEncoding) leavesOut(t reflect.Type) bool {
+	for _, m := range []ValueMethod{MethodJSONMarshaler, MethodTextMarshaler} {
+		iface := methodInterfaces[m]
+		if t.Implements(iface) {
+			if !slices.Contains(e.precedence, m) {
+				return true
+			}
+		} else if reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateEncoder implements [jsoniter.Extension].
+func (e *valueEncoding) CreateEncoder(typ reflect2.Type) jsoniter.ValEncoder {
+	choice := e.choose(typ.Type1())
+	switch choice.kind {
+	case chooseEncoder:
+		return &registeredEncoder{typ: typ, encoder: choice.encoder}
+	case chooseMethod:
+		return &methodEncoder{typ: typ, method: choice.method}
+	case chooseReflection:
+		return &reflectionEncoder{typ: typ}
+	default:
+		return nil
+	}
+}
+
+// resolve resolves a [slog.LogValuer] if the precedence calls for its LogValue method. Otherwise, the value is left
+// for [writeAny] to write according to its type.
+func (h *baseHandler) resolve(v slog.Value) slog.Value {
+	if v.Kind() != slog.KindLogValuer {
+		return v
+	}
+	choice := h.encoding.choose(reflect.TypeOf(v.Any()))
+	if choice.kind == chooseMethod && choice.method == MethodLogValuer {
+		return v.Resolve()
+	}
+	return v
+}
+
+// methodEncoder writes a value with one of its methods.
+type methodEncoder struct {
+	typ    reflect2.Type
+	method ValueMethod
+}
+
+func (e *methodEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	v := reflect.ValueOf(e.typ.UnsafeIndirect(ptr))
+	return !v.IsValid() || v.IsZero()
+}
+
+func (e *methodEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	v := e.typ.UnsafeIndirect(ptr)
+	if isNil(v) {
+		stream.WriteNil()
+		return
+	}
+	switch e.method {
+	case MethodLogValuer:
+		writeLogValue(stream, v.(slog.LogValuer).LogValue().Resolve()) //revive:disable-line:unchecked-type-assertion
+	case MethodJSONMarshaler:
+		writeMarshaledJSON(stream, v.(json.Marshaler)) //revive:disable-line:unchecked-type-assertion
+	case MethodTextMarshaler:
+		text, err := v.(encoding.TextMarshaler).MarshalText() //revive:disable-line:unchecked-type-assertion
+		if err != nil {
+			writeErrorMarkerTo(stream, err)
+			return
+		}
+		stream.WriteString(string(text))
+	case MethodStringer:
+		stream.WriteString(v.(fmt.Stringer).String()) //revive:disable-line:unchecked-type-assertion
+	}
+}
+
+// writeLogValue writes a resolved [slog.Value] nested within another value, with groups as objects and durations and
+// times as strings, as the handler writes them at the top level.
+func writeLogValue(stream *jsoniter.Stream, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindGroup:
+		stream.WriteObjectStart()
+		for i, a := range v.Group() {
+			if i > 0 {
+				stream.WriteMore()
+			}
+			stream.WriteObjectField(a.Key)
+			writeLogValue(stream, a.Value.Resolve())
+		}
+		stream.WriteObjectEnd()
+	case slog.KindDuration:
+		stream.WriteString(v.Duration().String())
+	case slog.KindTime:
+		stream.WriteString(v.Time().String())
+	default:
+		stream.WriteVal(v.Any())
+	}
+}
+
+// writeMarshaledJSON writes the result of a MarshalJSON method, compacted, or a marker if it fails or isn't valid.
+func writeMarshaledJSON(stream *jsoniter.Stream, m json.Marshaler) {
+	raw, err := m.MarshalJSON()
+	if err != nil {
+		writeErrorMarkerTo(stream, err)
+		return
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		writeErrorMarkerTo(stream, fmt.Errorf("invalid JSON from MarshalJSON for %T", m))
+		return
+	}
+	stream.WriteRaw(compact.String())
+}
+
+// reflectionEncoder writes a value by reflection even though its type has a MarshalJSON or MarshalText method, which
+// jsoniter would otherwise call. It copies the value into an equivalent type without methods and writes that instead.
+type reflectionEncoder struct {
+	typ reflect2.Type
+}
+
+func (e *reflectionEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	v := reflect.ValueOf(e.typ.UnsafeIndirect(ptr))
+	return !v.IsValid() || v.IsZero()
+}
+
+func (e *reflectionEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	v := reflect.ValueOf(e.typ.UnsafeIndirect(ptr))
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if isNil(v.Interface()) {
+		stream.WriteNil()
+		return
+	}
+	plain, ok := withoutMethods(v)
+	if !ok {
+		writeErrorMarkerTo(stream, fmt.Errorf("can't encode %s without its methods", e.typ))
+		return
+	}
+	stream.WriteVal(plain.Interface())
+}
+
+// basicTypes holds the unnamed type of each basic kind.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeFor[bool](),
+	reflect.Int:     reflect.TypeFor[int](),
+	reflect.Int8:    reflect.TypeFor[int8](),
+	reflect.Int16:   reflect.TypeFor[int16](),
+	reflect.Int32:   reflect.TypeFor[int32](),
+	reflect.Int64:   reflect.TypeFor[int64](),
+	reflect.Uint:    reflect.TypeFor[uint](),
+	reflect.Uint8:   reflect.TypeFor[uint8](),
+	reflect.Uint16:  reflect.TypeFor[uint16](),
+	reflect.Uint32:  reflect.TypeFor[uint32](),
+	reflect.Uint64:  reflect.TypeFor[uint64](),
+	reflect.Uintptr: reflect.TypeFor[uintptr](),
+	reflect.Float32: reflect.TypeFor[float32](),
+	reflect.Float64: reflect.TypeFor[float64](),
+	reflect.String:  reflect.TypeFor[string](),
+}
+
+// withoutMethods converts v to its underlying type, which has no methods. A struct keeps only its exported fields, the
+// ones that would be encoded anyway; embedded fields become ordinary fields, since their methods would be promoted.
+func withoutMethods(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Struct:
+		return structWithoutMethods(v), true
+	case reflect.Array:
+		return v.Convert(reflect.ArrayOf(t.Len(), t.Elem())), true
+	case reflect.Slice:
+		return v.Convert(reflect.SliceOf(t.Elem())), true
+	case reflect.Map:
+		return v.Convert(reflect.MapOf(t.Key(), t.Elem())), true
+	default:
+		basic, ok := basicTypes[t.Kind()]
+		if !ok {
+			return v, false
+		}
+		return v.Convert(basic), true
+	}
+}
+
+func structWithoutMethods(v reflect.Value) reflect.Value {
+	t := v.Type()
+	var fields []reflect.StructField
+	var index []int
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag})
+		index = append(index, i)
+	}
+	plain := reflect.New(reflect.StructOf(fields)).Elem()
+	for to, from := range index {
+		plain.Field(to).Set(v.Field(from))
+	}
+	return plain
+}
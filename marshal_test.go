@@ -0,0 +1,192 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+type shelf struct {
+	Row, Column int
+}
+
+func (s shelf) String() string {
+	return "shelf"
+}
+
+type slot [2]byte
+
+func (slot) MarshalText() ([]byte, error) {
+	return []byte("slot-text"), nil
+}
+
+func (slot) String() string {
+	return "slot-string"
+}
+
+type drive struct {
+	Serial string
+}
+
+func (drive) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "serial": "hidden" }`), nil
+}
+
+func (d drive) String() string {
+	return "drive " + d.Serial
+}
+
+type library struct {
+	Name string
+}
+
+func (l library) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", l.Name), slog.Int("slots", 2))
+}
+
+func (l library) String() string {
+	return "library " + l.Name
+}
+
+type echo struct{}
+
+func (e echo) LogValue() slog.Value {
+	return slog.AnyValue(e)
+}
+
+func TestMarshalPrecedence(t *testing.T) {
+	t.Parallel()
+
+	values := []any{
+		"shelf", shelf{1, 2},
+		"slot", slot{3, 4},
+		"drive", drive{"D1"},
+		"library", library{"L1"},
+		"nested", struct{ Library library }{library{"L2"}},
+		"echo", echo{},
+	}
+	for _, tc := range []struct {
+		name       string
+		precedence []nblog.ValueMethod
+		expected   string
+	}{
+		{
+			name: "default",
+			expected: `{"shelf": {"Row":1,"Column":2}, "slot": "slot-text", "drive": {"serial":"hidden"}, ` +
+				`"library": {"name": "L1", "slots": 2}, "nested": {"Library":{"name":"L2","slots":2}}, ` +
+				`"echo": "LogValue called too many times on Value of type nblog_test.echo"}`,
+		},
+		{
+			name:       "stringer first",
+			precedence: []nblog.ValueMethod{nblog.MethodStringer, nblog.MethodJSONMarshaler},
+			expected: `{"shelf": "shelf", "slot": "slot-string", "drive": "drive D1", "library": "library L1", ` +
+				`"nested": {"Library":"library L2"}, "echo": {}}`,
+		},
+		{
+			name:       "reflection",
+			precedence: []nblog.ValueMethod{},
+			expected: `{"shelf": {"Row":1,"Column":2}, "slot": [3,4], "drive": {"Serial":"D1"}, ` +
+				`"library": {"Name":"L1"}, "nested": {"Library":{"Name":"L2"}}, "echo": {}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			output := &LineBuffer{}
+			var opts []nblog.Option
+			if tc.precedence != nil {
+				opts = append(opts, nblog.MarshalPrecedence(tc.precedence...))
+			}
+			slog.New(nblog.New(output, opts...)).Info("values", values...)
+
+			g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(": values " + tc.expected)))
+		})
+	}
+}
+
+func TestStringerByDefault(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	slog.New(nblog.New(output)).Info("stringers",
+		"shelf", shelf{1, 2},
+		"pointer", &shelf{3, 4},
+		"nested", struct{ Shelves []shelf }{[]shelf{{5, 6}}},
+	)
+	slog.New(nblog.New(output, nblog.MarshalPrecedence(nblog.MethodStringer))).Info("stringers",
+		"shelf", shelf{1, 2},
+		"nested", struct{ Shelves []shelf }{[]shelf{{5, 6}}},
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		HaveSuffix(`: stringers {"shelf": {"Row":1,"Column":2}, "pointer": {"Row":3,"Column":4}, `+
+			`"nested": {"Shelves":[{"Row":5,"Column":6}]}}`),
+		HaveSuffix(`: stringers {"shelf": "shelf", "nested": {"Shelves":["shelf"]}}`),
+	))
+}
+
+type ptrJSON struct {
+	X int
+}
+
+func (*ptrJSON) MarshalJSON() ([]byte, error) {
+	return []byte(`"ptr-json"`), nil
+}
+
+type ptrText struct {
+	Y int
+}
+
+func (*ptrText) MarshalText() ([]byte, error) {
+	return []byte("ptr-text"), nil
+}
+
+func TestPointerReceiverMethods(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	slog.New(nblog.New(output)).Info("values",
+		"json", ptrJSON{1},
+		"text", ptrText{2},
+		"jsonPointer", &ptrJSON{3},
+		"textPointer", &ptrText{4},
+		"nested", struct {
+			J  ptrJSON
+			T  ptrText
+			JP *ptrJSON
+		}{ptrJSON{5}, ptrText{6}, &ptrJSON{7}},
+		"slices", struct {
+			J []ptrJSON
+			T []ptrText
+		}{[]ptrJSON{{8}}, []ptrText{{9}}},
+	)
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix(`: values {"json": {"X":1}, "text": {"Y":2}, ` +
+		`"jsonPointer": "ptr-json", "textPointer": "ptr-text", ` +
+		`"nested": {"J":{"X":5},"T":{"Y":6},"JP":"ptr-json"}, "slices": {"J":[{"X":8}],"T":[{"Y":9}]}}`)))
+}
+
+func TestStandardJSON(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.StandardJSON(true)))
+	counts := map[string]int{"zulu": 1, "alpha": 2, "mike": 3, "<b>": 4}
+
+	for range 3 {
+		logger.Info("counts", "counts", counts)
+	}
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		HaveSuffix(`: counts {"counts": {"<b>":4,"alpha":2,"mike":3,"zulu":1}}`),
+		HaveSuffix(`: counts {"counts": {"<b>":4,"alpha":2,"mike":3,"zulu":1}}`),
+		HaveSuffix(`: counts {"counts": {"<b>":4,"alpha":2,"mike":3,"zulu":1}}`),
+	))
+}
@@ -0,0 +1,279 @@
+package nblog
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Merger reads several legacy log files and yields their entries as a single stream ordered by timestamp. Typically,
+// each file comes from a different process. The merge is stable: entries with equal timestamps appear in the order of
+// the files given to [NewMerger], and entries from the same file keep their relative order.
+//
+// Lines that can't be parsed, and entries whose timestamps can't be parsed, are assumed to belong with the preceding
+// entry from the same file, so they take on that entry's timestamp for ordering purposes.
+//
+// Files written with [TimeOnlyFormat] have no date in their timestamps. For those, the Merger takes the date from the
+// file name, which is expected to contain a NetBackup-style MMDDYY date, as in "101824_00001.log" or "log.101824".
+// When the time of day goes backward by more than twelve hours within such a file, the Merger assumes the log has
+// crossed midnight and advances the date.
+type Merger struct {
+	sources mergeHeap
+	current *mergeSource
+	err     error
+}
+
+// mergeSource is one input to a Merger.
+type mergeSource struct {
+	name    string
+	index   int
+	closer  io.Closer
+	scanner *Scanner
+	date    time.Time // from the file name; zero if none. Only the year, month, and day are meaningful.
+
+	entry    Entry
+	entryErr error
+	when     time.Time // the ordering key for entry
+	days     int       // days added for midnight crossings
+	lastTime time.Time // the most recent parsed timestamp, before adjustment
+}
+
+// NewMerger opens the named files for merging. A directory name stands for all the regular files in that directory.
+// Compressed files are decompressed transparently, as by [OpenLog].
+// Set parser options through [Merger.SetParser] before the first call to [Merger.Scan].
+func NewMerger(paths ...string) (*Merger, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	m := &Merger{}
+	for _, name := range files {
+		f, err := OpenLog(name)
+		if err != nil {
+			_ = m.Close()
+			return nil, err
+		}
+		m.add(name, f, f)
+	}
+	return m, nil
+}
+
+// NewReaderMerger merges already-open readers. The names tag each entry's Source and supply the date for logs written
+// with [TimeOnlyFormat]; they needn't refer to real files.
+func NewReaderMerger(readers map[string]io.Reader) *Merger {
+	m := &Merger{}
+	names := make([]string, 0, len(readers))
+	for name := range readers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		m.add(name, readers[name], nil)
+	}
+	return m
+}
+
+func (m *Merger) add(name string, r io.Reader, closer io.Closer) {
+	m.sources = append(m.sources, &mergeSource{
+		name:    name,
+		index:   len(m.sources),
+		closer:  closer,
+		scanner: NewScanner(r),
+		date:    dateFromFileName(name),
+	})
+}
+
+func expandPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// dateInFileName matches an MMDDYY date in a file name.
+var dateInFileName = regexp.MustCompile(`(?:^|\D)(\d\d)(\d\d)(\d\d)(?:\D|$)`)
+
+// dateFromFileName finds an MMDDYY date in the base name of a file.
+func dateFromFileName(name string) time.Time {
+	for _, match := range dateInFileName.FindAllStringSubmatch(filepath.Base(name), -1) {
+		month, _ := strconv.Atoi(match[1])
+		day, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		date := time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if date.Month() == time.Month(month) && date.Day() == day {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// SetParser configures how every input's lines are parsed. Call it before the first call to [Merger.Scan].
+func (m *Merger) SetParser(p LineParser) {
+	for _, src := range m.sources {
+		src.scanner.Parser = p
+	}
+}
+
+// Scan advances to the next entry in timestamp order, which is then available through [Merger.Entry]. It returns false
+// when all the inputs are exhausted or when reading fails; [Merger.Err] distinguishes the two.
+func (m *Merger) Scan() bool {
+	if m.err != nil {
+		return false
+	}
+	if m.current == nil {
+		m.start()
+	} else if m.current.advance() {
+		heap.Fix(&m.sources, 0)
+	} else {
+		m.err = m.current.scanner.Err()
+		heap.Pop(&m.sources)
+	}
+	if m.err != nil || len(m.sources) == 0 {
+		m.current = nil
+		return false
+	}
+	m.current = m.sources[0]
+	return true
+}
+
+// start reads the first entry from each input and arranges the inputs by their first timestamps.
+func (m *Merger) start() {
+	live := m.sources[:0]
+	for _, src := range m.sources {
+		if src.advance() {
+			live = append(live, src)
+		} else if err := src.scanner.Err(); err != nil {
+			m.err = errors.Join(m.err, err)
+		}
+	}
+	m.sources = live
+	heap.Init(&m.sources)
+}
+
+// Entry returns the current entry, with its Source field set to the name of the file it came from. The error is a
+// [*ParseError] if the line wasn't in the legacy format.
+func (m *Merger) Entry() (Entry, error) {
+	if m.current == nil {
+		return Entry{}, nil
+	}
+	return m.current.entry, m.current.entryErr
+}
+
+// Err returns the first error encountered while reading.
+func (m *Merger) Err() error {
+	return m.err
+}
+
+// Close closes all the files opened by [NewMerger].
+func (m *Merger) Close() error {
+	var errs []error
+	for _, src := range m.sources {
+		if src.closer != nil {
+			errs = append(errs, src.closer.Close())
+		}
+		src.closer = nil
+	}
+	return errors.Join(errs...)
+}
+
+// advance reads the next line from the source and computes its ordering key.
+func (src *mergeSource) advance() bool {
+	if !src.scanner.Scan() {
+		if src.closer != nil {
+			_ = src.closer.Close()
+			src.closer = nil
+		}
+		return false
+	}
+	src.entry, src.entryErr = src.scanner.Entry()
+	src.entry.Source = src.name
+	if !src.entry.Time.IsZero() {
+		src.entry.Time = src.addDate(src.entry.Time)
+		src.when = src.entry.Time
+	}
+	return true
+}
+
+// addDate supplies the date from the file name for timestamps that were written without one.
+func (src *mergeSource) addDate(t time.Time) time.Time {
+	if t.Year() != 0 || src.date.IsZero() {
+		return t
+	}
+	const midnightThreshold = -12 * time.Hour
+	if !src.lastTime.IsZero() && t.Sub(src.lastTime) < midnightThreshold {
+		src.days++
+	}
+	src.lastTime = t
+	return time.Date(src.date.Year(), src.date.Month(), src.date.Day()+src.days,
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// mergeHeap orders sources by the timestamps of their current entries, breaking ties by their original positions.
+type mergeHeap []*mergeSource
+
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].when.Equal(h[j].when) {
+		return h[i].when.Before(h[j].when)
+	}
+	return h[i].index < h[j].index
+}
+
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(*mergeSource)) //revive:disable-line:unchecked-type-assertion Only used by container/heap.
+}
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// Merge writes the lines of all the named files to w in timestamp order, as described for [Merger]. Each line is
+// prefixed with the name of the file it came from and a colon, like the output of grep with multiple files.
+func Merge(w io.Writer, paths ...string) error {
+	m, err := NewMerger(paths...)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	for m.Scan() {
+		entry, _ := m.Entry()
+		if _, err := io.WriteString(w, entry.Source+":"+entry.Line+"\n"); err != nil {
+			return err
+		}
+	}
+	return m.Err()
+}
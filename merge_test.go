@@ -0,0 +1,91 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestMergeIsStable(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	m := nblog.NewReaderMerger(map[string]io.Reader{
+		"a.log": strings.NewReader(heredoc.Doc(`
+			2024-11-22 15:00:01.000 [1] <INFO> f: a1
+			2024-11-22 15:00:03.000 [1] <INFO> f: a2
+			not a log line
+			2024-11-22 15:00:03.000 [1] <INFO> f: a3
+		`)),
+		"b.log": strings.NewReader(heredoc.Doc(`
+			2024-11-22 15:00:02.000 [2] <INFO> f: b1
+			2024-11-22 15:00:03.000 [2] <INFO> f: b2
+		`)),
+	})
+	var got []string
+	for m.Scan() {
+		entry, _ := m.Entry()
+		got = append(got, entry.Source+" "+entry.Line)
+	}
+	g.Expect(m.Err()).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal([]string{
+		"a.log 2024-11-22 15:00:01.000 [1] <INFO> f: a1",
+		"b.log 2024-11-22 15:00:02.000 [2] <INFO> f: b1",
+		"a.log 2024-11-22 15:00:03.000 [1] <INFO> f: a2",
+		"a.log not a log line",
+		"a.log 2024-11-22 15:00:03.000 [1] <INFO> f: a3",
+		"b.log 2024-11-22 15:00:03.000 [2] <INFO> f: b2",
+	}))
+}
+
+func TestMergeTimeOnlyFiles(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	m := nblog.NewReaderMerger(map[string]io.Reader{
+		"bpbrm/112224_00001.log": strings.NewReader(heredoc.Doc(`
+			23:59:59.000 [1] <INFO> f: before midnight
+			00:00:01.000 [1] <INFO> f: after midnight
+		`)),
+		"bptm/112324_00001.log": strings.NewReader(heredoc.Doc(`
+			00:00:00.000 [2] <INFO> f: midnight
+		`)),
+	})
+	m.SetParser(nblog.LineParser{Location: time.UTC})
+	var got []string
+	var times []time.Time
+	for m.Scan() {
+		entry, _ := m.Entry()
+		got = append(got, entry.Message)
+		times = append(times, entry.Time)
+	}
+	g.Expect(got).To(Equal([]string{"before midnight", "midnight", "after midnight"}))
+	g.Expect(times[0]).To(Equal(time.Date(2024, time.November, 22, 23, 59, 59, 0, time.UTC)))
+	g.Expect(times[2]).To(Equal(time.Date(2024, time.November, 23, 0, 0, 1, 0, time.UTC)))
+}
+
+func TestMergeDirectory(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		g.Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)).To(Succeed())
+	}
+	write("one.log", "2024-11-22 15:00:02.000 [1] <INFO> f: second\n")
+	write("two.log", "2024-11-22 15:00:01.000 [2] <INFO> f: first\n")
+
+	var out strings.Builder
+	g.Expect(nblog.Merge(&out, dir)).To(Succeed())
+	g.Expect(out.String()).To(Equal(
+		filepath.Join(dir, "two.log") + ":2024-11-22 15:00:01.000 [2] <INFO> f: first\n" +
+			filepath.Join(dir, "one.log") + ":2024-11-22 15:00:02.000 [1] <INFO> f: second\n"))
+}
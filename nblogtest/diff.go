@@ -0,0 +1,91 @@
+package nblogtest
+
+import (
+	"strings"
+)
+
+// contextLines is how many unchanged lines [Diff] shows around each change.
+const contextLines = 2
+
+// Diff compares two texts line by line. It returns a listing in which lines only in want are marked with "-", lines only
+// in got with "+", and unchanged lines with a space. Runs of unchanged lines far from any change are elided with
+// "...". It returns an empty string if the texts are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	lines := diffLines(splitLines(want), splitLines(got))
+	var b strings.Builder
+	elided := false
+	for i, line := range lines {
+		if line[0] == ' ' && !nearChange(lines, i) {
+			if !elided {
+				b.WriteString("...\n")
+			}
+			elided = true
+			continue
+		}
+		elided = false
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines, marking a missing final newline so that it shows up in the diff.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += " (no newline at end)"
+	return lines
+}
+
+// diffLines produces the marked lines of a minimal diff, using the longest common subsequence of the two inputs.
+func diffLines(want, got []string) []string {
+	// common[i][j] is the length of the longest common subsequence of want[i:] and got[j:].
+	common := make([][]int, len(want)+1)
+	for i := range common {
+		common[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var lines []string
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			lines = append(lines, "  "+want[i])
+			i++
+			j++
+		case i < len(want) && (j == len(got) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, "- "+want[i])
+			i++
+		default:
+			lines = append(lines, "+ "+got[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// nearChange reports whether the line at index i is within contextLines of a changed line.
+func nearChange(lines []string, i int) bool {
+	for k := max(0, i-contextLines); k <= min(len(lines)-1, i+contextLines); k++ {
+		if lines[k][0] != ' ' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+// Package nblogtest helps test programs that log through [nblog] by comparing their log output to golden files.
+//
+// A [Capture] is an nblog handler whose output doesn't vary from run to run: every record has the same timestamp,
+// process ID, host name, and program name. Callers are named by their functions, which don't vary either. A test logs
+// through it and then compares the output to a golden file in the package's testdata directory:
+//
+//	func TestBackup(t *testing.T) {
+//		c := nblogtest.NewCapture()
+//		runBackup(c.Logger())
+//		c.AssertGolden(t, "backup")
+//	}
+//
+// Run the tests with the -nblogtest.update flag to write the golden files from the current output. If the test binary
+// defines its own boolean -update flag, that works too.
+package nblogtest
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"sweetkennedy.net/nblog"
+)
+
+// These are the values that a [Capture] substitutes for the parts of each line that would otherwise vary.
+const (
+	Pid     = 1234
+	Host    = "host"
+	Program = "program"
+)
+
+// Time is the timestamp that a [Capture] gives every record.
+var Time = time.Date(2024, time.November, 22, 15, 0, 0, 0, time.UTC)
+
+// update has a name of its own, since test binaries often define an -update flag for their own golden files.
+var update = flag.Bool("nblogtest.update", false, "rewrite golden files in testdata with the current log output")
+
+// updating reports whether to rewrite golden files: with -nblogtest.update, or with an -update flag defined by the test
+// binary.
+func updating() bool {
+	if *update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	set, _ := getter.Get().(bool)
+	return set
+}
+
+// Capture collects the output of an nblog handler configured to produce the same output on every run. It's safe for
+// concurrent use, though records logged concurrently may be captured in any order.
+type Capture struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	handler slog.Handler
+}
+
+// NewCapture creates a handler that writes to a new Capture. The options are applied as for [nblog.New]; any
+// replacement functions added with [nblog.ReplaceAttr] see the substituted values.
+func NewCapture(opts ...nblog.Option) *Capture {
+	c := &Capture{}
+	c.handler = nblog.New(c, append([]nblog.Option{nblog.ReplaceAttr(deterministic)}, opts...)...)
+	return c
+}
+
+// deterministic replaces the values that vary from run to run.
+func deterministic(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.Time(a.Key, Time)
+	case nblog.PidKey:
+		return slog.Int(a.Key, Pid)
+	case nblog.HostKey:
+		return slog.String(a.Key, Host)
+	case nblog.ProgramKey:
+		return slog.String(a.Key, Program)
+	}
+	return a
+}
+
+// Handler returns the handler that writes to c.
+func (c *Capture) Handler() slog.Handler {
+	return c.handler
+}
+
+// Logger returns a logger that writes to c.
+func (c *Capture) Logger() *slog.Logger {
+	return slog.New(c.handler)
+}
+
+// Write implements [io.Writer] as the handler's destination.
+func (c *Capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// String returns the output captured so far.
+func (c *Capture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// AssertGolden compares the output captured so far to the named golden file, as [AssertGolden] does.
+func (c *Capture) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+	AssertGolden(t, name, c.String())
+}
+
+// AssertGolden compares got to the contents of testdata/name.golden, relative to the current directory, which is the
+// package directory while tests run. If they differ, the test fails with a line-by-line diff. With the
+// -nblogtest.update flag, it writes got to the file instead, creating the testdata directory if necessary.
+func AssertGolden(t testing.TB, name, got string) {
+	t.Helper()
+	file := filepath.Join("testdata", name+".golden")
+	if updating() {
+		const dirPermissions, filePermissions = 0o755, 0o644
+		if err := os.MkdirAll(filepath.Dir(file), dirPermissions); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(got), filePermissions); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v (run the test with -nblogtest.update to create it)", err)
+	}
+	if string(want) != got {
+		t.Errorf("log output differs from %s (-want +got):\n%s", file, Diff(string(want), got))
+	}
+}
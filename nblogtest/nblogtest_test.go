@@ -0,0 +1,73 @@
+package nblogtest_test
+
+//revive:disable:add-constant
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+	"sweetkennedy.net/nblog/nblogtest"
+)
+
+// ownUpdate is a flag of the test binary's own, as many define; the package's flag must not collide with it.
+var ownUpdate = flag.Bool("update", false, "rewrite golden files")
+
+func TestCaptureGolden(t *testing.T) {
+	t.Parallel()
+
+	c := nblogtest.NewCapture(nblog.ShowHostname(true), nblog.ShowProgram(true), nblog.Level(slog.LevelDebug))
+	logger := c.Logger().With("job", 42)
+	logger.Debug("starting", "client", "alpha")
+	func() {
+		logger.WithGroup("media").Info("mounted", "drive", 3)
+	}()
+	logger.Error("failed", "status", 96)
+
+	c.AssertGolden(t, "capture")
+}
+
+func TestCaptureFullCallerName(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	c := nblogtest.NewCapture(nblog.UseFullCallerName(true))
+	c.Logger().Info("message")
+	g.Expect(c.String()).To(Equal(
+		"2024-11-22 15:00:00.000 [1234] <INFO> sweetkennedy.net/nblog/nblogtest_test.TestCaptureFullCallerName: message\n"))
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	g.Expect(nblogtest.Diff("a\nb\n", "a\nb\n")).To(BeEmpty())
+	g.Expect(nblogtest.Diff("1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nfive\n6\n7\n8\n")).To(Equal(
+		"...\n" +
+			"  3\n" +
+			"  4\n" +
+			"- 5\n" +
+			"+ five\n" +
+			"  6\n" +
+			"  7\n" +
+			"...\n"))
+	g.Expect(nblogtest.Diff("a\n", "a")).To(Equal("- a\n+ a (no newline at end)\n"))
+	g.Expect(nblogtest.Diff("", "a\n")).To(Equal("+ a\n"))
+}
+
+func TestAssertGoldenOwnUpdateFlag(t *testing.T) {
+	g := NewWithT(t)
+
+	t.Chdir(t.TempDir())
+	g.Expect(flag.Set("update", "true")).To(Succeed())
+	defer func() { *ownUpdate = false }()
+
+	nblogtest.AssertGolden(t, "updated", "line\n")
+
+	content, err := os.ReadFile(filepath.Join("testdata", "updated.golden"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(content)).To(Equal("line\n"))
+}
@@ -0,0 +1,358 @@
+package nblog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is one record parsed from a legacy log line. Fields that were omitted from the line (for example, because a
+// [ReplaceAttrFunc] removed them) have their zero values.
+type Entry struct {
+	// Time is the parsed timestamp. It is zero if the timestamp was missing or couldn't be parsed with any of the
+	// parser's layouts. TimeText holds the timestamp exactly as it appeared in the line.
+	Time     time.Time
+	TimeText string
+
+	// Host and Program are the host name and program name, if the handler included them. See [ShowHostname] and
+	// [ShowProgram].
+	Host    string
+	Program string
+	Pid     string
+
+	// Level is the record's severity. LevelText holds the label exactly as it appeared between the angle brackets,
+	// which may be a name like "INFO" or a number if the handler used [NumericSeverity].
+	Level     slog.Level
+	LevelText string
+
+	Caller  string
+	Message string
+
+	// Attrs holds the attributes from the JSON tail, in their original order. JSON objects become [slog.KindGroup]
+	// values, integers become [slog.KindInt64] (or [slog.KindUint64] if too big), other numbers become
+	// [slog.KindFloat64], and arrays and nulls become [slog.KindAny].
+	Attrs []slog.Attr
+
+	// Line is the original text of the log line, without its line terminator.
+	Line string
+	// Source names the file the line came from. Only [Merger] sets it.
+	Source string
+}
+
+// ParseError reports a line that isn't in the legacy log format.
+type ParseError struct {
+	Line   string
+	Reason string
+	// Number is the 1-based line number within the input. It's zero when the line didn't come from a [Scanner].
+	Number int
+}
+
+// Error implements [error].
+func (e *ParseError) Error() string {
+	if e.Number > 0 {
+		return fmt.Sprintf("cannot parse log line %d: %s: %q", e.Number, e.Reason, e.Line)
+	}
+	return fmt.Sprintf("cannot parse log line: %s: %q", e.Reason, e.Line)
+}
+
+// headerPattern matches everything before the caller and message: "time host prog[pid] <sev> ". The timestamp and host
+// name are matched together and separated later.
+var headerPattern = regexp.MustCompile(`^(?:(.*?) )?(\S*)\[([^\]]*)\] <([^>]*)> `)
+
+// callerPattern matches a caller name at the start of the remaining text: a function name, optionally qualified with
+// its package path and receiver type as [UseFullCallerName] writes it, followed by a colon. Anything else, such as an
+// address or a file name, is taken as the start of a message that has no caller.
+var callerPattern = regexp.MustCompile(`^((?:[\w.~-]+/)*` + callerSegment + `(?:\.` + callerSegment + `)*): `)
+
+// callerSegment matches one dotted part of a function name: an identifier, possibly with the type parameter marker of
+// a generic function, or a receiver type in parentheses.
+const callerSegment = `(?:[\p{L}_][\p{L}\p{N}_]*(?:\[\.\.\.\])?|\(\*?[\p{L}_][\p{L}\p{N}_]*(?:\[\.\.\.\])?\))`
+
+// LineParser parses lines written by a [New] handler back into their component parts.
+type LineParser struct {
+	// Layouts lists the timestamp layouts to try, in order. If nil, the parser tries [FullDateFormat] and
+	// [TimeOnlyFormat], each with and without the zone offset added by [ShowTimeZone].
+	Layouts []string
+	// Location is the time zone assumed for timestamps that don't specify one. If nil, the parser uses [time.Local].
+	Location *time.Location
+}
+
+// ParseLine parses a single log line with the default [LineParser] settings.
+func ParseLine(line string) (Entry, error) {
+	var p LineParser
+	return p.Parse(line)
+}
+
+// Parse splits line into its timestamp, host name, program name, process ID, severity, caller, message, and
+// attributes. A line that lacks the "[pid] <sev>" header yields a [*ParseError]. A timestamp that matches none of the
+// layouts isn't an error; the entry simply has a zero Time.
+func (p *LineParser) Parse(line string) (Entry, error) {
+	line = strings.TrimRight(line, "\r\n")
+	entry := Entry{Line: line}
+	match := headerPattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return entry, &ParseError{Line: line, Reason: "missing [pid] <severity> header"}
+	}
+	entry.TimeText, entry.Host, entry.Time = p.splitHost(submatch(line, match, 1))
+	entry.Program = submatch(line, match, 2)
+	entry.Pid = submatch(line, match, 3)
+	entry.LevelText = submatch(line, match, 4)
+	entry.Level = parseLevel(entry.LevelText)
+
+	rest := line[match[1]:]
+	if caller := callerPattern.FindStringSubmatch(rest); caller != nil {
+		entry.Caller = caller[1]
+		rest = rest[len(caller[0]):]
+	}
+	entry.Message, entry.Attrs = splitAttributes(rest)
+	return entry, nil
+}
+
+func submatch(s string, match []int, n int) string {
+	if match[2*n] < 0 {
+		return ""
+	}
+	return s[match[2*n]:match[2*n+1]]
+}
+
+// splitHost separates the host name from the timestamp that precedes it. If the whole text is a valid timestamp, there
+// is no host name. Otherwise, the last word is the host name if the rest is a valid timestamp. If neither is the case,
+// the text is all taken as an unparsable timestamp.
+func (p *LineParser) splitHost(text string) (string, string, time.Time) {
+	t := p.parseTime(text)
+	if !t.IsZero() {
+		return text, "", t
+	}
+	if i := strings.LastIndexByte(text, ' '); i >= 0 {
+		if t := p.parseTime(text[:i]); !t.IsZero() {
+			return text[:i], text[i+1:], t
+		}
+	}
+	return text, "", time.Time{}
+}
+
+func (p *LineParser) parseTime(text string) time.Time {
+	if text == "" {
+		return time.Time{}
+	}
+	layouts := p.Layouts
+	if layouts == nil {
+		layouts = []string{FullDateFormat, TimeOnlyFormat, FullDateFormat + zoneSuffix, TimeOnlyFormat + zoneSuffix}
+	}
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, text, loc)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseLevel interprets a severity label. It accepts the names produced by [slog.Level.String] as well as the numbers
+// produced by [NumericSeverity]. Unrecognized labels are treated as [slog.LevelInfo].
+func parseLevel(text string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(text)); err == nil {
+		return level
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil && n > 0 {
+		return unscaleLevel(n)
+	}
+	return slog.LevelInfo
+}
+
+// splitAttributes separates the message from the JSON tail. The tail starts at the first " {" for which the rest of
+// the line is a single valid JSON object; messages may themselves contain braces.
+func splitAttributes(rest string) (string, []slog.Attr) {
+	for start := 0; ; {
+		idx := strings.Index(rest[start:], " {")
+		if idx < 0 {
+			return rest, nil
+		}
+		idx += start
+		attrs, err := decodeAttributes(rest[idx+1:])
+		if err == nil {
+			return rest[:idx], attrs
+		}
+		start = idx + 1
+	}
+}
+
+// decodeAttributes decodes a JSON object into an ordered list of attributes.
+func decodeAttributes(text string) ([]slog.Attr, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	attrs, err := decodeObject(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("trailing data after attributes")
+	}
+	return attrs, nil
+}
+
+func decodeObject(dec *json.Decoder) ([]slog.Attr, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected object, found %v", tok)
+	}
+	return decodeMembers(dec)
+}
+
+// decodeMembers decodes the members of an object whose opening brace has already been consumed.
+func decodeMembers(dec *json.Decoder) ([]slog.Attr, error) {
+	attrs := []slog.Attr{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		value, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: value})
+	}
+	_, err := dec.Token() // closing brace
+	return attrs, err
+}
+
+func decodeValue(dec *json.Decoder) (slog.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		attrs, err := decodeMembers(dec)
+		return slog.GroupValue(attrs...), err
+	case json.Delim('['):
+		var elements []any
+		for dec.More() {
+			var element any
+			if err := dec.Decode(&element); err != nil {
+				return slog.Value{}, err
+			}
+			elements = append(elements, element)
+		}
+		_, err := dec.Token() // closing bracket
+		return slog.AnyValue(elements), err
+	default:
+		return jsonValue(tok), nil
+	}
+}
+
+// jsonValue converts a value decoded by [json.Decoder.Decode] into a [slog.Value].
+func jsonValue(v any) slog.Value {
+	switch v := v.(type) {
+	case string:
+		return slog.StringValue(v)
+	case bool:
+		return slog.BoolValue(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return slog.Int64Value(i)
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return slog.Uint64Value(u)
+		}
+		f, _ := v.Float64()
+		return slog.Float64Value(f)
+	default:
+		return slog.AnyValue(v)
+	}
+}
+
+// Lookup finds an attribute by its dotted path, such as "catalog.size" for the attribute "size" inside the group
+// "catalog".
+func (e *Entry) Lookup(path string) (slog.Value, bool) {
+	attrs := e.Attrs
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		attr, ok := findAttr(attrs, key)
+		if !ok {
+			return slog.Value{}, false
+		}
+		if i == len(keys)-1 {
+			return attr.Value, true
+		}
+		if attr.Value.Kind() != slog.KindGroup {
+			return slog.Value{}, false
+		}
+		attrs = attr.Value.Group()
+	}
+	return slog.Value{}, false
+}
+
+// findAttr returns the last attribute with the given key, matching the way most JSON parsers treat duplicate keys.
+func findAttr(attrs []slog.Attr, key string) (slog.Attr, bool) {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if attrs[i].Key == key {
+			return attrs[i], true
+		}
+	}
+	return slog.Attr{}, false
+}
+
+// maxLineLength is the longest line a [Scanner] will accept.
+const maxLineLength = 64 << 20
+
+// Scanner reads legacy log lines from an [io.Reader] and parses each one. Its interface resembles [bufio.Scanner].
+type Scanner struct {
+	// Parser is used to interpret each line. Change it before the first call to Scan.
+	Parser LineParser
+
+	lines  *bufio.Scanner
+	number int
+	entry  Entry
+	err    error
+}
+
+// NewScanner returns a Scanner that reads from r.
+func NewScanner(r io.Reader) *Scanner {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(nil, maxLineLength)
+	return &Scanner{lines: lines}
+}
+
+// Scan advances to the next line, which is then available through [Scanner.Entry]. It returns false at the end of the
+// input or when reading fails; [Scanner.Err] distinguishes the two.
+func (s *Scanner) Scan() bool {
+	if !s.lines.Scan() {
+		return false
+	}
+	s.number++
+	s.entry, s.err = s.Parser.Parse(s.lines.Text())
+	var perr *ParseError
+	if errors.As(s.err, &perr) {
+		perr.Number = s.number
+	}
+	return true
+}
+
+// Entry returns the most recently scanned entry. The error is a [*ParseError] if the line wasn't in the legacy format;
+// in that case, only the entry's Line field is set.
+func (s *Scanner) Entry() (Entry, error) {
+	return s.entry, s.err
+}
+
+// Err returns the first non-EOF error encountered while reading.
+func (s *Scanner) Err() error {
+	return s.lines.Err()
+}
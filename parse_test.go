@@ -0,0 +1,159 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.ReplaceAttr(UniformOutput)))
+	logger.With(slog.Int("jobid", 42)).WithGroup("catalog").Warn("size: {big}",
+		slog.Int("size", 1500),
+		slog.Group("image", slog.String("name", "client_1700000000"), slog.Bool("tir", false)),
+		slog.Float64("ratio", 0.5),
+	)
+
+	entry, err := nblog.ParseLine(output.Lines[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entry.Time).To(BeTemporally("==", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.Local)))
+	g.Expect(entry.Pid).To(Equal("42"))
+	g.Expect(entry.Level).To(Equal(slog.LevelWarn))
+	g.Expect(entry.Caller).To(Equal("TestParseRoundTrip"))
+	g.Expect(entry.Message).To(Equal("size: {big}"))
+	g.Expect(entry.Attrs).To(HaveLen(2))
+
+	size, ok := entry.Lookup("catalog.size")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(size.Kind()).To(Equal(slog.KindInt64))
+	g.Expect(size.Int64()).To(Equal(int64(1500)))
+
+	name, ok := entry.Lookup("catalog.image.name")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(name.String()).To(Equal("client_1700000000"))
+
+	ratio, _ := entry.Lookup("catalog.ratio")
+	g.Expect(ratio.Kind()).To(Equal(slog.KindFloat64))
+
+	_, ok = entry.Lookup("catalog.missing")
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestParseNumericSeverity(t *testing.T) {
+	t.Parallel()
+
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		t.Run(level.String(), func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			output := &LineBuffer{}
+			logger := slog.New(nblog.New(output, nblog.Level(slog.LevelDebug), nblog.NumericSeverity(true)))
+			logger.Log(t.Context(), level, "message")
+
+			entry, err := nblog.ParseLine(output.Lines[0])
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(entry.Level).To(Equal(level))
+		})
+	}
+}
+
+func TestParseMissingFields(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	entry, err := nblog.ParseLine("[7] <INFO> no caller here")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entry.Time.IsZero()).To(BeTrue())
+	g.Expect(entry.Pid).To(Equal("7"))
+	g.Expect(entry.Caller).To(BeEmpty())
+	g.Expect(entry.Message).To(Equal("no caller here"))
+	g.Expect(entry.Attrs).To(BeEmpty())
+}
+
+func TestParseCallers(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		line    string
+		caller  string
+		message string
+	}{
+		{"[7] <INFO> func1: done", "func1", "done"},
+		{"[7] <INFO> sweetkennedy.net/nblog_test.TestX.func1: done", "sweetkennedy.net/nblog_test.TestX.func1", "done"},
+		{"[7] <INFO> main.(*server).handle: done", "main.(*server).handle", "done"},
+		{"[7] <INFO> example.com/my-mod/pkg.Map[...]: done", "example.com/my-mod/pkg.Map[...]", "done"},
+		{"[7] <INFO> 10.0.0.1: host unreachable", "", "10.0.0.1: host unreachable"},
+		{"[7] <INFO> /var/log: disk full", "", "/var/log: disk full"},
+		{"[7] <INFO> [job 5]: started", "", "[job 5]: started"},
+		{"[7] <INFO> retrying in 5s: connection refused", "", "retrying in 5s: connection refused"},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			entry, err := nblog.ParseLine(tc.line)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(entry.Caller).To(Equal(tc.caller))
+			g.Expect(entry.Message).To(Equal(tc.message))
+		})
+	}
+}
+
+func TestParseCallerlessRecord(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	h := nblog.New(output)
+	g.Expect(h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "10.0.0.1: host unreachable", 0))).
+		To(Succeed())
+
+	entry, err := nblog.ParseLine(output.Lines[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entry.Caller).To(BeEmpty())
+	g.Expect(entry.Message).To(Equal("10.0.0.1: host unreachable"))
+}
+
+func TestParseError(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	_, err := nblog.ParseLine("not a log line")
+	var perr *nblog.ParseError
+	g.Expect(err).To(BeAssignableToTypeOf(perr))
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	input := strings.Join([]string{
+		"10:00:00.000 [1] <INFO> f: one",
+		"garbage",
+		"10:00:01.000 [1] <ERROR> f: two {\"a\": [1, 2]}",
+	}, "\n")
+	scanner := nblog.NewScanner(strings.NewReader(input))
+	var messages []string
+	var failures int
+	for scanner.Scan() {
+		entry, err := scanner.Entry()
+		if err != nil {
+			failures++
+			continue
+		}
+		messages = append(messages, entry.Message)
+	}
+	g.Expect(scanner.Err()).NotTo(HaveOccurred())
+	g.Expect(messages).To(Equal([]string{"one", "two"}))
+	g.Expect(failures).To(Equal(1))
+}
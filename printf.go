@@ -0,0 +1,76 @@
+package nblog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Logger is a [slog.Logger] with additional printf-style methods in the manner of NetBackup's dprintf. The formatting
+// happens only if the handler is enabled for the record's level, so disabled debug calls cost little. Records identify
+// the function that called the method as their caller.
+type Logger struct {
+	*slog.Logger
+}
+
+// NewLogger creates a Logger for the given handler.
+func NewLogger(h slog.Handler) *Logger {
+	return &Logger{slog.New(h)}
+}
+
+// VerbosityLevel maps a NetBackup debug verbosity onto a [slog.Level]. Verbosity zero and below map to
+// [slog.LevelInfo], so such messages appear whenever the log is on at all. Verbosity 1 maps to [slog.LevelDebug], and
+// each further step of verbosity is one level lower, so VerbosityLevel(5) is [slog.LevelDebug]-4. To see messages up
+// to a given verbosity, configure the handler with [Level](VerbosityLevel(verbosity)).
+func VerbosityLevel(verbosity int) slog.Level {
+	if verbosity <= 0 {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug - slog.Level(verbosity-1)
+}
+
+// With returns a Logger that includes the given attributes in each record, as for [slog.Logger.With].
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// WithGroup returns a Logger that starts a group, as for [slog.Logger.WithGroup].
+func (l *Logger) WithGroup(name string) *Logger {
+	return &Logger{l.Logger.WithGroup(name)}
+}
+
+// Debugf logs a formatted message at the level corresponding to the given verbosity, per [VerbosityLevel].
+func (l *Logger) Debugf(verbosity int, format string, args ...any) {
+	l.logf(VerbosityLevel(verbosity), format, args)
+}
+
+// Infof logs a formatted message at [slog.LevelInfo].
+func (l *Logger) Infof(format string, args ...any) {
+	l.logf(slog.LevelInfo, format, args)
+}
+
+// Warnf logs a formatted message at [slog.LevelWarn].
+func (l *Logger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args)
+}
+
+// Errorf logs a formatted message at [slog.LevelError].
+func (l *Logger) Errorf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args)
+}
+
+func (l *Logger) logf(level slog.Level, format string, args []any) {
+	ctx := context.Background()
+	h := l.Handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	// Skip runtime.Callers, this method, and the exported method that called it.
+	const skip = 3
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	r := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
+	_ = h.Handle(ctx, r)
+}
@@ -0,0 +1,55 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// Counter counts how many times it's formatted.
+type Counter struct {
+	Formatted int
+}
+
+func (c *Counter) String() string {
+	c.Formatted++
+	return "counter"
+}
+
+func TestPrintfMethods(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	logger := nblog.NewLogger(nblog.New(output, nblog.Level(nblog.VerbosityLevel(3)))).With("job", 7)
+
+	counter := &Counter{}
+	logger.Debugf(0, "verbosity %d", 0)
+	logger.Debugf(3, "verbosity %d", 3)
+	logger.Debugf(4, "verbosity %d: %s", 4, counter)
+	logger.Infof("info %q", "quoted")
+	logger.Warnf("warn %.1f", 1.5)
+	logger.Errorf("error %v", true)
+
+	g.Expect(counter.Formatted).To(BeZero())
+	g.Expect(output.Lines).To(HaveExactElements(
+		HaveSuffix(`<INFO> TestPrintfMethods: verbosity 0 {"job": 7}`),
+		HaveSuffix(`<DEBUG-2> TestPrintfMethods: verbosity 3 {"job": 7}`),
+		HaveSuffix(`<INFO> TestPrintfMethods: info "quoted" {"job": 7}`),
+		HaveSuffix(`<WARN> TestPrintfMethods: warn 1.5 {"job": 7}`),
+		HaveSuffix(`<ERROR> TestPrintfMethods: error true {"job": 7}`),
+	))
+}
+
+func TestVerbosityLevel(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	g.Expect(nblog.VerbosityLevel(-1)).To(Equal(slog.LevelInfo))
+	g.Expect(nblog.VerbosityLevel(0)).To(Equal(slog.LevelInfo))
+	g.Expect(nblog.VerbosityLevel(1)).To(Equal(slog.LevelDebug))
+	g.Expect(nblog.VerbosityLevel(5)).To(Equal(slog.LevelDebug - 4))
+}
@@ -0,0 +1,152 @@
+package nblog
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// maxFlightRings limits how many rings a keyed flight recorder keeps. When a new key needs a ring beyond the limit, the
+// least recently used ring is discarded.
+const maxFlightRings = 256
+
+// FlightRecorder makes the handler keep the most recent records that fall below its level instead of discarding them,
+// up to the given number of records. They're rendered in the legacy format as they arrive and kept in memory. When a
+// record at [slog.LevelError] or above is logged, the kept records are written to the destination first, oldest first,
+// so the error arrives with the debug context that led up to it. Records that are never followed by an error are
+// eventually overwritten by newer ones. Zero or less disables the recorder, which is the default.
+//
+// Since the handler can't tell in advance which records it will keep, [slog.Handler.Enabled] reports true for every
+// level while the recorder is in effect, and every record is formatted. Records the handler writes about itself, such
+// as those from [Banner] and [LevelHandler], are never kept.
+func FlightRecorder(records int) Option {
+	return func(h slog.Handler) {
+		base(h).flightSize = records
+	}
+}
+
+// FlightRecorderKey gives the [FlightRecorder] a separate ring for each value stored in a record's context under the
+// given key, such as a job ID, so an error flushes only the records that share its context value. Records whose
+// context has no value for the key, or a value that isn't comparable, share one ring. Without this option, all records
+// share one ring.
+func FlightRecorderKey(key any) Option {
+	return func(h slog.Handler) {
+		base(h).flightKey = key
+	}
+}
+
+// setUpFlightRecorder creates the recorder once all the options are known.
+func (h *baseHandler) setUpFlightRecorder() {
+	if h.flightSize <= 0 {
+		return
+	}
+	h.recorder = &flightRecorder{
+		size:  h.flightSize,
+		key:   h.flightKey,
+		rings: map[any]*flightRing{},
+	}
+}
+
+// flightRecorder holds the records kept by [FlightRecorder].
+type flightRecorder struct {
+	size int
+	key  any // the context key that selects a ring; nil if there's only one ring
+
+	mu    sync.Mutex
+	rings map[any]*flightRing
+	uses  uint64 // counts the uses of rings, to find the least recently used
+}
+
+// flightRing is a circular buffer of rendered records.
+type flightRing struct {
+	records []flightRecord
+	start   int // the index of the oldest record once the buffer is full
+	lastUse uint64
+}
+
+// flightRecord is a rendered record and its description for the destination.
+type flightRecord struct {
+	line []byte
+	info RecordInfo
+}
+
+// keep renders the record and adds it to the ring for its context.
+func (r *flightRecorder) keep(
+	ctx context.Context, h *baseHandler, out *jsonStream, record slog.Record, writeNested nestedCallback,
+) error {
+	if err := h.render(out, record, writeNested); err != nil {
+		return err
+	}
+	key := r.ringKey(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ring := r.rings[key]
+	if ring == nil {
+		r.evict()
+		ring = &flightRing{}
+		r.rings[key] = ring
+	}
+	r.uses++
+	ring.lastUse = r.uses
+	ring.add(flightRecord{out.Buffer(), recordInfo(out, record)}, r.size)
+	return nil
+}
+
+// replay writes out and forgets the records kept for the context.
+func (r *flightRecorder) replay(ctx context.Context, h *baseHandler) {
+	key := r.ringKey(ctx)
+	r.mu.Lock()
+	ring := r.rings[key]
+	delete(r.rings, key)
+	r.mu.Unlock()
+	if ring == nil {
+		return
+	}
+	for _, rec := range ring.ordered() {
+		// Failures are reported by emit like any others; the error record still gets its turn.
+		_ = h.emit(rec.line, rec.info)
+	}
+}
+
+// ringKey returns the key of the ring for records logged with ctx.
+func (r *flightRecorder) ringKey(ctx context.Context) any {
+	if r.key == nil || ctx == nil {
+		return nil
+	}
+	value := ctx.Value(r.key)
+	if value == nil || !reflect.ValueOf(value).Comparable() {
+		return nil
+	}
+	return value
+}
+
+// evict discards the least recently used ring if there's no room for another.
+func (r *flightRecorder) evict() {
+	if len(r.rings) < maxFlightRings {
+		return
+	}
+	var oldestKey any
+	var oldest *flightRing
+	for key, ring := range r.rings {
+		if oldest == nil || ring.lastUse < oldest.lastUse {
+			oldestKey, oldest = key, ring
+		}
+	}
+	delete(r.rings, oldestKey)
+}
+
+func (ring *flightRing) add(rec flightRecord, size int) {
+	if len(ring.records) < size {
+		ring.records = append(ring.records, rec)
+		return
+	}
+	ring.records[ring.start] = rec
+	ring.start = (ring.start + 1) % size
+}
+
+// ordered returns the records from oldest to newest.
+func (ring *flightRing) ordered() []flightRecord {
+	return slices.Concat(ring.records[ring.start:], ring.records[:ring.start])
+}
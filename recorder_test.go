@@ -0,0 +1,66 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.FlightRecorder(3)))
+	g.Expect(logger.Enabled(context.Background(), slog.LevelDebug)).To(BeTrue())
+
+	for i := range 5 {
+		logger.Debug("step", "i", i)
+	}
+	logger.Info("progress")
+	g.Expect(output.Lines()).To(HaveExactElements(HaveSuffix("<INFO> TestFlightRecorder: progress")))
+
+	logger.Error("failed")
+	logger.Debug("after")
+	logger.Error("failed again")
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		HaveSuffix("<INFO> TestFlightRecorder: progress"),
+		HaveSuffix(`<DEBUG> TestFlightRecorder: step {"i": 2}`),
+		HaveSuffix(`<DEBUG> TestFlightRecorder: step {"i": 3}`),
+		HaveSuffix(`<DEBUG> TestFlightRecorder: step {"i": 4}`),
+		HaveSuffix("<ERROR> TestFlightRecorder: failed"),
+		HaveSuffix("<DEBUG> TestFlightRecorder: after"),
+		HaveSuffix("<ERROR> TestFlightRecorder: failed again"),
+	))
+	g.Expect(logger.Handler().(statser).Stats().Debug).To(BeEquivalentTo(4))
+}
+
+type jobKey struct{}
+
+func TestFlightRecorderKey(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.FlightRecorder(10), nblog.FlightRecorderKey(jobKey{})))
+	job1 := context.WithValue(context.Background(), jobKey{}, 1)
+	job2 := context.WithValue(context.Background(), jobKey{}, 2)
+
+	logger.DebugContext(job1, "job 1 detail")
+	logger.DebugContext(job2, "job 2 detail")
+	logger.Debug("no job detail")
+	logger.With("job", 1).ErrorContext(job1, "job 1 failed")
+	logger.Error("no job failed")
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		HaveSuffix("<DEBUG> TestFlightRecorderKey: job 1 detail"),
+		HaveSuffix(`<ERROR> TestFlightRecorderKey: job 1 failed {"job": 1}`),
+		HaveSuffix("<DEBUG> TestFlightRecorderKey: no job detail"),
+		HaveSuffix("<ERROR> TestFlightRecorderKey: no job failed"),
+	))
+}
@@ -0,0 +1,43 @@
+package nblog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Record converts the entry into a [slog.Record] with the entry's time, level, message, and attributes. Groups in the
+// JSON tail become nested [slog.Group] attributes. The record has no program counter because the original caller can't
+// be recovered from its name, and the process ID and caller aren't included; add them as attributes if they're needed.
+func (e *Entry) Record() slog.Record {
+	r := slog.NewRecord(e.Time, e.Level, e.Message, 0)
+	r.AddAttrs(e.Attrs...)
+	return r
+}
+
+// Replay reads legacy log lines from r and passes each one to h as a [slog.Record], as built by [Entry.Record]. Records
+// that h isn't [slog.Handler.Enabled] for are skipped, just as a [slog.Logger] would skip them. This makes it possible
+// to feed existing logs into another handler, such as [slog.JSONHandler] or a [New] handler with different options.
+//
+// Replay stops at the first error from reading r or from h. Lines that can't be parsed don't stop the replay; they are
+// reported in the returned error, which joins a [*ParseError] for each of them.
+func Replay(r io.Reader, h slog.Handler) error {
+	ctx := context.Background()
+	scanner := NewScanner(r)
+	var skipped []error
+	for scanner.Scan() {
+		entry, err := scanner.Entry()
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		if !h.Enabled(ctx, entry.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, entry.Record()); err != nil {
+			return errors.Join(append(skipped, err)...)
+		}
+	}
+	return errors.Join(append(skipped, scanner.Err())...)
+}
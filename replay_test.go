@@ -0,0 +1,97 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+var replayInput = heredoc.Doc(`
+	2024-11-22 15:00:07.398 [100] <DEBUG> Start: hidden
+	2024-11-22 15:00:08.000 [100] <INFO> Start: shown {"jobid": 42, "catalog": {"size": 2048, "ratio": 0.5, "tir": true}}
+	garbage
+	2024-11-22 15:00:09.000 [100] <WARN> Start: also shown {"paths": ["/a", "/b"]}
+`)
+
+func TestReplayIntoJSON(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	var output strings.Builder
+	// Drop the timestamps since their rendering depends on the local time zone.
+	h := slog.NewJSONHandler(&output, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	err := nblog.Replay(strings.NewReader(replayInput), h)
+
+	var perr *nblog.ParseError
+	g.Expect(errors.As(err, &perr)).To(BeTrue())
+	g.Expect(perr.Number).To(Equal(3))
+	g.Expect(perr.Line).To(Equal("garbage"))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	g.Expect(lines).To(HaveLen(2))
+	g.Expect(lines[0]).To(MatchJSON(`{
+		"level": "INFO", "msg": "shown",
+		"jobid": 42, "catalog": {"size": 2048, "ratio": 0.5, "tir": true}
+	}`))
+	g.Expect(lines[1]).To(MatchJSON(`{
+		"level": "WARN", "msg": "also shown", "paths": ["/a", "/b"]
+	}`))
+}
+
+func TestReplayIntoLegacy(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	h := nblog.New(output,
+		nblog.TimestampFormat(nblog.TimeOnlyFormat),
+		nblog.NumericSeverity(true),
+		nblog.ReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == nblog.PidKey {
+				return slog.Attr{}
+			}
+			return a
+		}),
+	)
+	_ = nblog.Replay(strings.NewReader(replayInput), h)
+
+	g.Expect(output.Lines).To(Equal([]string{
+		`15:00:08.000 <4> shown {"jobid": 42, "catalog": {"size": 2048, "ratio": 0.5, "tir": true}}`,
+		`15:00:09.000 <8> also shown {"paths": ["/a","/b"]}`,
+	}))
+}
+
+func TestEntryRecord(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	entry, err := nblog.ParseLine(`2024-11-22 15:00:08.000 [1] <ERROR> f: m {"g": {"a": 1}}`)
+	g.Expect(err).NotTo(HaveOccurred())
+	r := entry.Record()
+	g.Expect(r.Level).To(Equal(slog.LevelError))
+	g.Expect(r.Message).To(Equal("m"))
+	g.Expect(r.Time.Hour()).To(Equal(15))
+	g.Expect(r.Time.Location()).To(Equal(time.Local))
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	g.Expect(attrs).To(HaveLen(1))
+	g.Expect(attrs[0].Value.Kind()).To(Equal(slog.KindGroup))
+	g.Expect(attrs[0].Value.Group()[0].Value.Int64()).To(Equal(int64(1)))
+}
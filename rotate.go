@@ -0,0 +1,468 @@
+package nblog
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// segmentPattern matches the names of the files a [RotatingFile] writes: MMDDYY_NNNNN.log, possibly compressed.
+var segmentPattern = regexp.MustCompile(`^(\d{6})_(\d{5})\.log(\.gz|\.zst)?$`)
+
+// segmentDateLayout formats the date part of a log file name.
+const segmentDateLayout = "010206"
+
+// RotatingFile is a destination for [New] that writes to a directory of log files named in the NetBackup style,
+// MMDDYY_NNNNN.log, where MMDDYY is the date and NNNNN counts the files started that day. It starts a new file at
+// midnight and, with [MaxFileSize], whenever the current file would grow beyond the limit. When it starts, it continues
+// the latest of the day's files if that file still has room.
+//
+// Files that are no longer being written can be compressed, and old files can be removed according to their age and
+// the total size of the directory. That cleanup happens in the background after each rotation, when the RotatingFile
+// is opened, and on the schedule set with [CleanupInterval]. Each file it removes, and any failure, is logged through
+// the logger set with [RotationLogger]. Compressed files can be read with [OpenLog], [NewMerger], and nblogcat.
+//
+// Only one process should write to a directory at a time. Each file is written through a [File], configured with
+// [SegmentOptions], so a RotatingFile is safe for concurrent use by multiple goroutines.
+type RotatingFile struct {
+	dir             string
+	maxSize         int64
+	compression     Compression
+	keepDays        int
+	maxTotalSize    int64
+	cleanupInterval time.Duration
+	logger          *slog.Logger
+	fileOptions     []FileOption
+	now             func() time.Time
+
+	mu      sync.Mutex
+	current *File
+	date    string // the date part of the current file's name
+	number  int    // the sequence number of the current file
+	size    int64  // the size of the current file, including what's been buffered
+	closed  bool
+
+	cleanupMu sync.Mutex
+	tasks     sync.WaitGroup
+	done      chan struct{}
+}
+
+var _ LevelWriter = &RotatingFile{}
+
+// RotateOption is a function that can be passed to [OpenRotatingFile] to configure a new [RotatingFile].
+type RotateOption func(*RotatingFile)
+
+// MaxFileSize sets the size, in bytes, beyond which a log file won't grow; records that don't fit go in a new file. A
+// record bigger than the limit gets a file to itself. Zero or less means there is no limit, which is the default.
+func MaxFileSize(size int64) RotateOption {
+	return func(r *RotatingFile) {
+		r.maxSize = size
+	}
+}
+
+// Compress sets how rotated files are compressed. The default is [CompressNone].
+func Compress(c Compression) RotateOption {
+	return func(r *RotatingFile) {
+		r.compression = c
+	}
+}
+
+// KeepDays removes log files last modified more than the given number of days ago, like NetBackup's KEEP_LOGS_DAYS
+// setting. Zero or less means files are kept regardless of age, which is the default.
+func KeepDays(days int) RotateOption {
+	return func(r *RotatingFile) {
+		r.keepDays = days
+	}
+}
+
+// MaxTotalSize removes the oldest log files whenever the files in the directory, compressed or not, add up to more
+// than the given number of bytes. The file currently being written is never removed. Zero or less means there is no
+// limit, which is the default.
+func MaxTotalSize(size int64) RotateOption {
+	return func(r *RotatingFile) {
+		r.maxTotalSize = size
+	}
+}
+
+// CleanupInterval sets how often compression and retention are applied, in addition to after each rotation. The
+// default is one hour. Zero or less disables the scheduled cleanup.
+func CleanupInterval(interval time.Duration) RotateOption {
+	return func(r *RotatingFile) {
+		r.cleanupInterval = interval
+	}
+}
+
+// RotationLogger sets the logger that reports removed files and cleanup failures. By default, they go to
+// [slog.Default] at the time of the report.
+func RotationLogger(logger *slog.Logger) RotateOption {
+	return func(r *RotatingFile) {
+		r.logger = logger
+	}
+}
+
+// SegmentOptions sets the options for opening each log file, such as [FileBuffer].
+func SegmentOptions(opts ...FileOption) RotateOption {
+	return func(r *RotatingFile) {
+		r.fileOptions = opts
+	}
+}
+
+// OpenRotatingFile opens the latest log file in the directory, or starts a new one, creating the directory if
+// necessary.
+func OpenRotatingFile(dir string, opts ...RotateOption) (*RotatingFile, error) {
+	const dirPermissions = 0o755
+	r := &RotatingFile{
+		dir:             dir,
+		maxSize:         0,
+		compression:     CompressNone,
+		keepDays:        0,
+		maxTotalSize:    0,
+		cleanupInterval: time.Hour,
+		logger:          nil,
+		fileOptions:     nil,
+		now:             time.Now,
+		done:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return nil, err
+	}
+	if err := r.openLatest(); err != nil {
+		return nil, err
+	}
+	r.startCleanup()
+	return r, nil
+}
+
+// Name returns the name of the file currently being written.
+func (r *RotatingFile) Name() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.currentName()
+}
+
+func (r *RotatingFile) currentName() string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s_%05d.log", r.date, r.number))
+}
+
+// Write appends p to the current file as a record at [slog.LevelInfo].
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	return r.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel appends p to the current file, first starting a new one if the date has changed or p doesn't fit.
+func (r *RotatingFile) WriteLevel(level slog.Level, p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	// If opening the next file failed earlier, try again.
+	if r.current == nil || r.needsRotation(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.current.WriteLevel(level, p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new log file immediately.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+// Flush writes out any records buffered for the current file.
+func (r *RotatingFile) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Flush()
+}
+
+// Close stops the scheduled cleanup, waits for any cleanup in progress, and closes the current file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	if r.closed || r.stopping() {
+		r.mu.Unlock()
+		return os.ErrClosed
+	}
+	close(r.done)
+	r.mu.Unlock()
+	// Cleanup may log through a handler that writes to this file, so the file stays open until it's finished.
+	r.tasks.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+func (r *RotatingFile) needsRotation(n int) bool {
+	if r.now().Format(segmentDateLayout) != r.date {
+		return true
+	}
+	return r.maxSize > 0 && r.size > 0 && r.size+int64(n) > r.maxSize
+}
+
+// rotate closes the current file, opens the next one, and starts a cleanup in the background.
+func (r *RotatingFile) rotate() error {
+	if r.current != nil {
+		if err := r.current.Close(); err != nil {
+			// Report it later, because the report may be logged to this file.
+			name := r.currentName()
+			r.inBackground(func() { r.report("closing log file", name, err) })
+		}
+		r.current = nil
+	}
+	date := r.now().Format(segmentDateLayout)
+	number := 1
+	if date == r.date {
+		number = r.number + 1
+	}
+	if err := r.open(date, number); err != nil {
+		return err
+	}
+	r.inBackground(r.cleanup)
+	return nil
+}
+
+// openLatest opens the most recent of today's files if it can take more records, or else starts the next file.
+func (r *RotatingFile) openLatest() error {
+	date := r.now().Format(segmentDateLayout)
+	number, compressed, err := r.latestNumber(date)
+	if err != nil {
+		return err
+	}
+	if number == 0 || compressed {
+		number++
+	} else if info, err := os.Stat(filepath.Join(r.dir, fmt.Sprintf("%s_%05d.log", date, number))); err == nil &&
+		r.maxSize > 0 && info.Size() >= r.maxSize {
+		number++
+	}
+	return r.open(date, number)
+}
+
+// latestNumber finds the highest sequence number among the files for the given date, and whether that file has been
+// compressed. The number is zero if there are no such files.
+func (r *RotatingFile) latestNumber(date string) (int, bool, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return 0, false, err
+	}
+	latest, compressed := 0, false
+	for _, entry := range entries {
+		match := segmentPattern.FindStringSubmatch(entry.Name())
+		if match == nil || match[1] != date {
+			continue
+		}
+		if number, _ := strconv.Atoi(match[2]); number > latest || (number == latest && match[3] != "") {
+			latest, compressed = number, match[3] != ""
+		}
+	}
+	return latest, compressed, nil
+}
+
+func (r *RotatingFile) open(date string, number int) error {
+	name := filepath.Join(r.dir, fmt.Sprintf("%s_%05d.log", date, number))
+	f, err := OpenFile(name, r.fileOptions...)
+	if err != nil {
+		return err
+	}
+	var size int64
+	if info, err := os.Stat(name); err == nil {
+		size = info.Size()
+	}
+	r.current, r.date, r.number, r.size = f, date, number, size
+	return nil
+}
+
+// startCleanup runs a cleanup now and starts the scheduled ones.
+func (r *RotatingFile) startCleanup() {
+	r.inBackground(r.cleanup)
+	if r.cleanupInterval <= 0 {
+		return
+	}
+	r.tasks.Add(1)
+	go func() {
+		defer r.tasks.Done()
+		ticker := time.NewTicker(r.cleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				r.cleanup()
+			}
+		}
+	}()
+}
+
+// stopping reports whether [RotatingFile.Close] has begun.
+func (r *RotatingFile) stopping() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// inBackground runs task on another goroutine, which [RotatingFile.Close] waits for. Once Close has begun, new tasks
+// are skipped, since Close may already have finished waiting. It must be called with r.mu held, or before r is shared.
+func (r *RotatingFile) inBackground(task func()) {
+	if r.stopping() {
+		return
+	}
+	r.tasks.Add(1)
+	go func() {
+		defer r.tasks.Done()
+		task()
+	}()
+}
+
+// segment is one of the log files in the directory.
+type segment struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// cleanup compresses the files that are no longer being written and then applies the retention limits.
+func (r *RotatingFile) cleanup() {
+	r.cleanupMu.Lock()
+	defer r.cleanupMu.Unlock()
+	segments, err := r.segments()
+	if err != nil {
+		r.report("listing log files", r.dir, err)
+		return
+	}
+	// The current file is read after the listing, so a file opened by a rotation in between isn't listed at all.
+	current := r.Name()
+	segments = slices.DeleteFunc(segments, func(s segment) bool { return s.path == current })
+	if r.compression != CompressNone {
+		r.compressSegments(segments)
+	}
+	segments = r.removeExpired(segments)
+	r.enforceTotalSize(segments, current)
+}
+
+// segments lists the log files in the directory, oldest first.
+func (r *RotatingFile) segments() ([]segment, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for _, entry := range entries {
+		if !segmentPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{filepath.Join(r.dir, entry.Name()), info.Size(), info.ModTime()})
+	}
+	slices.SortStableFunc(segments, func(a, b segment) int {
+		return cmp.Or(a.modTime.Compare(b.modTime), cmp.Compare(a.path, b.path))
+	})
+	return segments, nil
+}
+
+// compressSegments compresses the uncompressed files among segments, updating their entries.
+func (r *RotatingFile) compressSegments(segments []segment) {
+	for i, s := range segments {
+		if filepath.Ext(s.path) != ".log" {
+			continue
+		}
+		compressed, err := compressFile(s.path, r.compression)
+		if err != nil {
+			r.report("compressing log file", s.path, err)
+			continue
+		}
+		segments[i].path = compressed
+		if info, err := os.Stat(compressed); err == nil {
+			segments[i].size = info.Size()
+		}
+	}
+}
+
+// removeExpired removes the files older than the KeepDays limit and returns the rest.
+func (r *RotatingFile) removeExpired(segments []segment) []segment {
+	if r.keepDays <= 0 {
+		return segments
+	}
+	cutoff := r.now().AddDate(0, 0, -r.keepDays)
+	return slices.DeleteFunc(segments, func(s segment) bool {
+		return s.modTime.Before(cutoff) && r.remove(s, "age")
+	})
+}
+
+// enforceTotalSize removes the oldest files until the directory fits in the MaxTotalSize limit.
+func (r *RotatingFile) enforceTotalSize(segments []segment, current string) {
+	if r.maxTotalSize <= 0 {
+		return
+	}
+	var total int64
+	if info, err := os.Stat(current); err == nil {
+		total = info.Size()
+	}
+	for _, s := range segments {
+		total += s.size
+	}
+	for _, s := range segments {
+		if total <= r.maxTotalSize {
+			return
+		}
+		if r.remove(s, "size") {
+			total -= s.size
+		}
+	}
+}
+
+// remove deletes a log file and logs that it did. It reports whether the file is gone.
+func (r *RotatingFile) remove(s segment, reason string) bool {
+	if err := os.Remove(s.path); err != nil {
+		r.report("removing log file", s.path, err)
+		return false
+	}
+	r.log().Info("removed old log file", slog.String("file", s.path), slog.String("reason", reason))
+	return true
+}
+
+func (r *RotatingFile) report(action, path string, err error) {
+	r.log().Error(action+" failed", slog.String("file", path), slog.Any("error", err))
+}
+
+func (r *RotatingFile) log() *slog.Logger {
+	if r.logger != nil {
+		return r.logger
+	}
+	return slog.Default()
+}
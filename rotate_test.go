@@ -0,0 +1,183 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func readLog(g *WithT, name string) string {
+	r, err := nblog.OpenLog(name)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	g.Expect(err).ToNot(HaveOccurred())
+	return string(content)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	f, err := nblog.OpenRotatingFile(dir, nblog.MaxFileSize(100), nblog.CleanupInterval(0))
+	g.Expect(err).ToNot(HaveOccurred())
+	date := time.Now().Format("010206")
+	g.Expect(f.Name()).To(Equal(filepath.Join(dir, date+"_00001.log")))
+
+	line := strings.Repeat("x", 39) + "\n"
+	for range 5 {
+		_, err := f.Write([]byte(line))
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	g.Expect(f.Close()).To(Succeed())
+	_, err = f.Write([]byte(line))
+	g.Expect(err).To(MatchError(os.ErrClosed))
+
+	g.Expect(readLog(g, filepath.Join(dir, date+"_00001.log"))).To(Equal(line + line))
+	g.Expect(readLog(g, filepath.Join(dir, date+"_00002.log"))).To(Equal(line + line))
+	g.Expect(readLog(g, filepath.Join(dir, date+"_00003.log"))).To(Equal(line))
+
+	// Reopening continues the latest file because it still has room.
+	f, err = nblog.OpenRotatingFile(dir, nblog.MaxFileSize(100), nblog.CleanupInterval(0))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(f.Name()).To(Equal(filepath.Join(dir, date+"_00003.log")))
+	g.Expect(f.Close()).To(Succeed())
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		compression nblog.Compression
+		extension   string
+	}{
+		{nblog.CompressGzip, ".gz"},
+		{nblog.CompressZstd, ".zst"},
+	} {
+		t.Run(c.extension, func(t *testing.T) {
+			t.Parallel()
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			f, err := nblog.OpenRotatingFile(dir, nblog.Compress(c.compression), nblog.CleanupInterval(0))
+			g.Expect(err).ToNot(HaveOccurred())
+			logger := slog.New(nblog.New(f))
+			logger.Info("first")
+			first := f.Name()
+			g.Expect(f.Rotate()).To(Succeed())
+			logger.Info("second")
+			second := f.Name()
+			g.Expect(f.Close()).To(Succeed())
+
+			g.Expect(first).ToNot(BeAnExistingFile())
+			g.Expect(readLog(g, first+c.extension)).To(ContainSubstring("<INFO> func1: first\n"))
+			g.Expect(readLog(g, second)).To(ContainSubstring("<INFO> func1: second\n"))
+
+			m, err := nblog.NewMerger(dir)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer m.Close()
+			var messages []string
+			for m.Scan() {
+				entry, err := m.Entry()
+				g.Expect(err).ToNot(HaveOccurred())
+				messages = append(messages, entry.Message)
+			}
+			g.Expect(m.Err()).ToNot(HaveOccurred())
+			g.Expect(messages).To(Equal([]string{"first", "second"}))
+		})
+	}
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	old := filepath.Join(dir, "010120_00001.log")
+	g.Expect(os.WriteFile(old, []byte("old\n"), 0o600)).To(Succeed())
+	longAgo := time.Now().AddDate(0, 0, -10)
+	g.Expect(os.Chtimes(old, longAgo, longAgo)).To(Succeed())
+	unrelated := filepath.Join(dir, "notes.txt")
+	g.Expect(os.WriteFile(unrelated, []byte("keep me\n"), 0o600)).To(Succeed())
+
+	output := &SyncLineBuffer{}
+	f, err := nblog.OpenRotatingFile(dir,
+		nblog.KeepDays(7),
+		nblog.MaxTotalSize(100),
+		nblog.CleanupInterval(0),
+		nblog.RotationLogger(slog.New(nblog.New(output))))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var names []string
+	line := strings.Repeat("x", 39) + "\n"
+	for range 3 {
+		names = append(names, f.Name())
+		_, err := f.Write([]byte(line + line))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(f.Rotate()).To(Succeed())
+	}
+	g.Expect(f.Close()).To(Succeed())
+
+	g.Expect(old).ToNot(BeAnExistingFile())
+	g.Expect(unrelated).To(BeAnExistingFile())
+	g.Expect(names[0]).ToNot(BeAnExistingFile())
+	g.Expect(names[1]).ToNot(BeAnExistingFile())
+	g.Expect(names[2]).To(BeAnExistingFile())
+	g.Expect(output.Lines()).To(ConsistOf(
+		MatchRegexp(`<INFO> .*: removed old log file \{"file": "[^"]*010120_00001\.log", "reason": "age"\}`),
+		MatchRegexp(`<INFO> .*: removed old log file \{"file": "[^"]*_00001\.log", "reason": "size"\}`),
+		MatchRegexp(`<INFO> .*: removed old log file \{"file": "[^"]*_00002\.log", "reason": "size"\}`),
+	))
+}
+
+// selfLog is a destination that writes to a [nblog.RotatingFile] once it's ready, so the file's cleanup can log to the
+// file itself.
+type selfLog struct {
+	ready chan struct{}
+	f     *nblog.RotatingFile
+}
+
+func (s *selfLog) Write(p []byte) (int, error) {
+	<-s.ready
+	return s.f.Write(p)
+}
+
+func TestRotatingFileCloseDuringCleanup(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	longAgo := time.Now().AddDate(0, 0, -10)
+	for i := range 5 {
+		old := filepath.Join(dir, fmt.Sprintf("010120_%05d.log", i+1))
+		g.Expect(os.WriteFile(old, []byte("old\n"), 0o600)).To(Succeed())
+		g.Expect(os.Chtimes(old, longAgo, longAgo)).To(Succeed())
+	}
+
+	self := &selfLog{ready: make(chan struct{})}
+	var failures []error
+	f, err := nblog.OpenRotatingFile(dir,
+		nblog.KeepDays(7),
+		nblog.CleanupInterval(0),
+		nblog.RotationLogger(slog.New(nblog.New(self,
+			nblog.OnError(func(err error) { failures = append(failures, err) })))))
+	g.Expect(err).ToNot(HaveOccurred())
+	name := f.Name()
+	self.f = f
+	close(self.ready)
+	g.Expect(f.Close()).To(Succeed())
+
+	g.Expect(failures).To(BeEmpty())
+	g.Expect(strings.Count(readLog(g, name), "removed old log file")).To(Equal(5))
+	g.Expect(f.Close()).To(MatchError(os.ErrClosed))
+}
@@ -0,0 +1,174 @@
+package nblog
+
+import (
+	"expvar"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
+)
+
+// Stats reports what a handler has done since it was created. Every handler created by [New], and every handler derived
+// from one, has a Stats method that returns the statistics for the whole family:
+//
+//	if s, ok := logger.Handler().(interface{ Stats() nblog.Stats }); ok {
+//		fmt.Println(s.Stats().Bytes)
+//	}
+type Stats struct {
+	// Debug, Info, Warn, and Error count the records written at each level. Levels between the standard ones count
+	// toward the next lower standard level, so [slog.LevelInfo]+2 counts as Info.
+	Debug, Info, Warn, Error uint64
+	// Filtered counts the calls to Enabled that returned false, plus the records that Handle discards because of the
+	// levels set with [VModule]. A [slog.Logger] makes one such call to Enabled for each record it discards for being
+	// below the handler's level, but calls made directly are counted too.
+	Filtered uint64
+	// Bytes counts the bytes written to the destination.
+	Bytes uint64
+	// WriteErrors counts records that couldn't be written to the destination, even after any retries.
+	WriteErrors uint64
+	// Handled counts the records that were formatted, and HandleTime is the total time spent formatting and writing
+	// them.
+	Handled    uint64
+	HandleTime time.Duration
+}
+
+// handlerStats holds the counters behind [Stats].
+type handlerStats struct {
+	debug, info, warn, error atomic.Uint64
+	filtered                 atomic.Uint64
+	bytes                    atomic.Uint64
+	writeErrors              atomic.Uint64
+	handled                  atomic.Uint64
+	handleNanos              atomic.Int64
+}
+
+func (s *handlerStats) written(level slog.Level, n int) {
+	switch {
+	case level >= slog.LevelError:
+		s.error.Add(1)
+	case level >= slog.LevelWarn:
+		s.warn.Add(1)
+	case level >= slog.LevelInfo:
+		s.info.Add(1)
+	default:
+		s.debug.Add(1)
+	}
+	s.bytes.Add(uint64(n))
+}
+
+func (s *handlerStats) handledIn(d time.Duration) {
+	s.handled.Add(1)
+	s.handleNanos.Add(int64(d))
+}
+
+func (s *handlerStats) snapshot() Stats {
+	return Stats{
+		Debug:       s.debug.Load(),
+		Info:        s.info.Load(),
+		Warn:        s.warn.Load(),
+		Error:       s.error.Load(),
+		Filtered:    s.filtered.Load(),
+		Bytes:       s.bytes.Load(),
+		WriteErrors: s.writeErrors.Load(),
+		Handled:     s.handled.Load(),
+		HandleTime:  time.Duration(s.handleNanos.Load()),
+	}
+}
+
+// Stats returns the handler's statistics.
+func (h *baseHandler) Stats() Stats {
+	return h.stats.snapshot()
+}
+
+// Stats returns the statistics of the handler this one was derived from.
+func (h *groupHandler) Stats() Stats {
+	return h.previousHandler.Stats()
+}
+
+// Stats returns the statistics of the handler this one was derived from.
+func (h *attrHandler) Stats() Stats {
+	return h.previousHandler.Stats()
+}
+
+// PublishStats makes the handler's [Stats] available through [expvar] under the given name, so they appear at
+// /debug/vars along with the program's other variables. A later handler published under the same name, such as one a
+// program creates when it reloads its configuration, takes the name over. Like [expvar.Publish], it panics if the name
+// is already in use by a variable that PublishStats didn't create.
+func PublishStats(name string) Option {
+	return func(h slog.Handler) {
+		publishedLock.Lock()
+		defer publishedLock.Unlock()
+		handler, ok := published[name]
+		if !ok {
+			handler = &atomic.Pointer[baseHandler]{}
+			expvar.Publish(name, expvar.Func(func() any { return handler.Load().Stats() }))
+			published[name] = handler
+		}
+		handler.Store(base(h))
+	}
+}
+
+var (
+	publishedLock sync.Mutex
+	// published holds, for each name passed to PublishStats, the handler whose statistics the variable reports.
+	published = map[string]*atomic.Pointer[baseHandler]{}
+)
+
+// StatsInterval makes the handler log a summary of its [Stats] at [slog.LevelInfo] every interval, regardless of its
+// level, until the handler is closed with [Close] or is no longer in use. Zero or less disables the summary, which is
+// the default.
+func StatsInterval(interval time.Duration) Option {
+	return func(h slog.Handler) {
+		base(h).statsInterval = interval
+	}
+}
+
+// startStatsSummary starts the goroutine that logs the periodic summary, once all the options are known. The goroutine
+// holds only a weak pointer to the handler, so it also stops once the handler is no longer reachable.
+func (h *baseHandler) startStatsSummary() {
+	if h.statsInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.statsInterval)
+	stop := make(chan struct{})
+	runtime.AddCleanup(h, func(stop chan struct{}) { close(stop) }, stop)
+	handler := weak.Make(h)
+	closing := h.closing
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-closing:
+				return
+			case <-ticker.C:
+				if h := handler.Value(); h != nil {
+					h.logStats()
+				}
+			}
+		}
+	}()
+}
+
+func (h *baseHandler) logStats() {
+	s := h.Stats()
+	var average time.Duration
+	if s.Handled > 0 {
+		average = s.HandleTime / time.Duration(s.Handled)
+	}
+	h.note("log statistics",
+		slog.Group("written",
+			slog.Uint64("debug", s.Debug),
+			slog.Uint64("info", s.Info),
+			slog.Uint64("warn", s.Warn),
+			slog.Uint64("error", s.Error),
+		),
+		slog.Uint64("filtered", s.Filtered),
+		slog.Uint64("bytes", s.Bytes),
+		slog.Uint64("write_errors", s.WriteErrors),
+		slog.Duration("average_handle_time", average),
+	)
+}
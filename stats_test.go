@@ -0,0 +1,107 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+type statser interface {
+	Stats() nblog.Stats
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &FlakyWriter{}
+	logger := slog.New(nblog.New(output)).WithGroup("g")
+
+	logger.Debug("filtered")
+	logger.Info("one")
+	logger.Log(t.Context(), slog.LevelInfo+2, "two")
+	logger.Warn("three")
+	output.Down = true
+	logger.Error("four")
+
+	s, ok := logger.Handler().(statser)
+	g.Expect(ok).To(BeTrue())
+	stats := s.Stats()
+	g.Expect(stats.Debug).To(BeZero())
+	g.Expect(stats.Info).To(BeEquivalentTo(2))
+	g.Expect(stats.Warn).To(BeEquivalentTo(1))
+	g.Expect(stats.Error).To(BeZero())
+	g.Expect(stats.Filtered).To(BeEquivalentTo(1))
+	g.Expect(stats.WriteErrors).To(BeEquivalentTo(1))
+	g.Expect(stats.Handled).To(BeEquivalentTo(4))
+	g.Expect(stats.HandleTime).To(BeNumerically(">", 0))
+	g.Expect(stats.Bytes).To(BeEquivalentTo(len(strings.Join(output.Lines, "\n")) + 1))
+}
+
+// publishRuns makes the expvar names unique across repeated runs of the tests, since they can't be unpublished.
+var publishRuns atomic.Int32
+
+func TestPublishStats(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	name := fmt.Sprintf("nblog_test_stats_%d", publishRuns.Add(1))
+	published := func() nblog.Stats {
+		v := expvar.Get(name)
+		g.Expect(v).ToNot(BeNil())
+		var stats nblog.Stats
+		g.Expect(json.Unmarshal([]byte(v.String()), &stats)).To(Succeed())
+		return stats
+	}
+
+	logger := slog.New(nblog.New(&LineBuffer{}, nblog.PublishStats(name)))
+	logger.Info("message")
+	g.Expect(published().Info).To(BeEquivalentTo(1))
+
+	rebuilt := slog.New(nblog.New(&LineBuffer{}, nblog.PublishStats(name)))
+	rebuilt.Info("message")
+	rebuilt.Info("message")
+	g.Expect(published().Info).To(BeEquivalentTo(2))
+}
+
+func TestStatsInterval(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	logger := slog.New(nblog.New(output, nblog.Level(slog.LevelError), nblog.StatsInterval(20*time.Millisecond)))
+	logger.Error("message")
+
+	g.Eventually(output.Lines).Should(ContainElement(MatchRegexp(
+		`<INFO> log statistics \{"written": \{"debug": 0, "info": \d+, "warn": 0, "error": 1\}, "filtered": 0, `,
+	)))
+	runtime.KeepAlive(logger)
+}
+
+func TestStatsIntervalStops(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	func() {
+		slog.New(nblog.New(output, nblog.StatsInterval(time.Millisecond))).Info("message")
+	}()
+
+	// Once the handler is collected, no more summaries arrive.
+	g.Eventually(func() int {
+		runtime.GC()
+		before := len(output.Lines())
+		time.Sleep(20 * time.Millisecond)
+		return len(output.Lines()) - before
+	}).Should(BeZero())
+}
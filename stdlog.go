@@ -0,0 +1,52 @@
+package nblog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SetDefault makes a logger with handler h the default for the program. It installs the logger with
+// [slog.SetDefault], and it redirects the output of the standard [log] package into h, recording each line at the
+// given level. Unlike the redirection done by slog.SetDefault alone, lines from the log package identify the function
+// that called log.Printf (or Println, Fatal, and so on) as their caller, so they look the same as records logged through
+// slog. The log package's own timestamp and source-location flags are cleared, since h supplies those; its prefix, if
+// any, stays at the start of the message. It returns the new default logger.
+func SetDefault(h slog.Handler, level slog.Leveler) *slog.Logger {
+	logger := slog.New(h)
+	slog.SetDefault(logger)
+	log.SetOutput(&logWriter{handler: h, level: level})
+	log.SetFlags(0)
+	return logger
+}
+
+// NewLogLogger returns a [log.Logger] that sends each line it's given to h as a record at the given level, identifying
+// the function that called the log.Logger method as the caller. It's for passing to libraries that accept a
+// *log.Logger, such as [net/http.Server].
+func NewLogLogger(h slog.Handler, level slog.Leveler) *log.Logger {
+	return log.New(&logWriter{handler: h, level: level}, "", 0)
+}
+
+// logWriter is the destination for a [log.Logger] that turns each line into a record.
+type logWriter struct {
+	handler slog.Handler
+	level   slog.Leveler
+}
+
+// Write implements [io.Writer.Write]. The log package calls it once per line.
+func (w *logWriter) Write(buf []byte) (int, error) {
+	level := w.level.Level()
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, level) {
+		return len(buf), nil
+	}
+	// Skip runtime.Callers, this method, log.(*Logger).output, and the log function the program called.
+	const skip = 4
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	r := slog.NewRecord(time.Now(), level, string(bytes.TrimSuffix(buf, []byte("\n"))), pcs[0])
+	return len(buf), w.handler.Handle(ctx, r)
+}
@@ -0,0 +1,53 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"log"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// TestSetDefault changes global state, so it doesn't run in parallel with other tests.
+func TestSetDefault(t *testing.T) {
+	g := NewWithT(t)
+
+	previous, flags, prefix := slog.Default(), log.Flags(), log.Prefix()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	})
+
+	output := &LineBuffer{}
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	logger := nblog.SetDefault(nblog.New(output, nblog.Level(slog.LevelInfo)), slog.LevelWarn)
+
+	log.Printf("from %s", "log")
+	slog.Info("from slog")
+	logger.Info("from logger")
+	g.Expect(slog.Default()).To(BeIdenticalTo(logger))
+
+	g.Expect(output.Lines).To(HaveExactElements(
+		MatchRegexp(`^\S+ \S+ \[\d+\] <WARN> TestSetDefault: from log$`),
+		MatchRegexp(`^\S+ \S+ \[\d+\] <INFO> TestSetDefault: from slog$`),
+		MatchRegexp(`^\S+ \S+ \[\d+\] <INFO> TestSetDefault: from logger$`),
+	))
+}
+
+func TestNewLogLogger(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &LineBuffer{}
+	var level slog.LevelVar
+	logger := nblog.NewLogLogger(nblog.New(output), &level)
+
+	logger.Println("shown")
+	level.Set(slog.LevelDebug)
+	logger.Print("hidden")
+
+	g.Expect(output.Lines).To(HaveExactElements(HaveSuffix("<INFO> TestNewLogLogger: shown")))
+}
@@ -0,0 +1,266 @@
+package nblog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogFacility identifies the kind of program sending syslog messages, as defined by RFC 5424.
+type SyslogFacility int
+
+// These are the syslog facilities most useful to applications.
+const (
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+// SyslogFraming selects the message format a [SyslogWriter] uses.
+type SyslogFraming int
+
+const (
+	// RFC5424 is the modern syslog protocol: "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - - MSG". On stream
+	// transports, each message is preceded by its length, per RFC 6587.
+	RFC5424 SyslogFraming = iota
+	// RFC3164 is the traditional BSD syslog format: "<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG". On stream
+	// transports, each message is terminated by a newline. When sending to the local daemon over a Unix socket, the
+	// hostname is omitted, as the daemon supplies it.
+	RFC3164
+)
+
+// syslogSeverity values, from RFC 5424.
+const (
+	severityEmergency = iota
+	severityAlert
+	severityCritical
+	severityError
+	severityWarning
+	severityNotice
+	severityInfo
+	severityDebug
+)
+
+// SyslogWriter is a destination for [New] that sends each log line to a syslog daemon. It implements [LevelWriter] so
+// that each message's syslog priority reflects the record's level. It is safe for concurrent use.
+//
+// If a write fails, the writer closes its connection and redials once before reporting the error. Later writes will
+// redial again, so the writer recovers when the daemon restarts.
+type SyslogWriter struct {
+	network  string
+	address  string
+	facility SyslogFacility
+	framing  SyslogFraming
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var _ LevelWriter = &SyslogWriter{}
+
+// SyslogOption is a function that can be passed to [NewSyslogWriter] to configure a new [SyslogWriter].
+type SyslogOption func(*SyslogWriter)
+
+// Facility sets the syslog facility. The default is [FacilityUser].
+func Facility(f SyslogFacility) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.facility = f
+	}
+}
+
+// Framing sets the syslog message format. The default is [RFC5424].
+func Framing(f SyslogFraming) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.framing = f
+	}
+}
+
+// Tag sets the APP-NAME (for [RFC5424]) or TAG (for [RFC3164]) field. The default is the base name of the program.
+func Tag(tag string) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.tag = tag
+	}
+}
+
+// localSyslogSockets are the places syslog daemons conventionally listen on Unix systems.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// NewSyslogWriter connects to a syslog daemon. The network may be "unixgram" or "unix" for a Unix datagram or stream
+// socket, or "udp" or "tcp" for a daemon listening on the network, typically on localhost. If network and address are
+// both empty, the writer connects to the local daemon through one of the conventional Unix sockets.
+func NewSyslogWriter(network, address string, opts ...SyslogOption) (*SyslogWriter, error) {
+	hostname, _ := os.Hostname()
+	w := &SyslogWriter{
+		network:  network,
+		address:  address,
+		facility: FacilityUser,
+		framing:  RFC5424,
+		tag:      filepath.Base(os.Args[0]),
+		hostname: hostname,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.network != "" || w.address != "" {
+		conn, err := net.Dial(w.network, w.address)
+		w.conn = conn
+		return err
+	}
+	var errs []error
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				w.network, w.address, w.conn = network, path, conn
+				return nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	return fmt.Errorf("nblog: no local syslog daemon found: %w", errors.Join(errs...))
+}
+
+// Write sends p as a message at [slog.LevelInfo].
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel sends p as a message whose priority combines the writer's facility with the syslog severity
+// corresponding to level. Levels map to severities the same way [NumericSeverity] maps them to NetBackup numbers:
+// NetBackup severity 2 (debug) is syslog debug, 4 (info) is info, 8 (warning) is warning, 16 (error) is err, and each
+// further doubling is one step more severe, up to emerg. Levels between info and warning are notice.
+func (w *SyslogWriter) WriteLevel(level slog.Level, p []byte) (int, error) {
+	msg := w.format(level, time.Now(), trimNewline(p))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.send(msg)
+	if err != nil {
+		// Reconnect and try once more, in case the daemon restarted.
+		if w.conn != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+		if err = w.connect(); err == nil {
+			err = w.send(msg)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) send(msg []byte) error {
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func trimNewline(p []byte) []byte {
+	if len(p) > 0 && p[len(p)-1] == '\n' {
+		return p[:len(p)-1]
+	}
+	return p
+}
+
+// format frames a message according to the writer's framing and transport.
+func (w *SyslogWriter) format(level slog.Level, now time.Time, msg []byte) []byte {
+	pri := int(w.facility)*8 + syslogSeverity(level)
+	pid := os.Getpid()
+	var header string
+	if w.framing == RFC3164 {
+		host := w.hostname + " "
+		if w.isUnix() {
+			host = ""
+		}
+		header = fmt.Sprintf("<%d>%s %s%s[%d]: ", pri, now.Format(time.Stamp), host, w.tag, pid)
+	} else {
+		header = fmt.Sprintf("<%d>1 %s %s %s %d - - ", pri, now.Format(time.RFC3339Nano), nilValue(w.hostname),
+			nilValue(w.tag), pid)
+	}
+	frame := append([]byte(header), msg...)
+	if !w.isStream() {
+		return frame
+	}
+	if w.framing == RFC3164 {
+		return append(frame, '\n')
+	}
+	return append([]byte(strconv.Itoa(len(frame))+" "), frame...)
+}
+
+// nilValue substitutes the RFC 5424 NILVALUE for empty header fields.
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (w *SyslogWriter) isUnix() bool {
+	return w.network == "unix" || w.network == "unixgram"
+}
+
+func (w *SyslogWriter) isStream() bool {
+	switch w.network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// syslogSeverity maps a level onto a syslog severity by way of the NetBackup severity number from scaleLevel.
+func syslogSeverity(level slog.Level) int {
+	const nbInfo, nbWarning = 4, 8
+	nb := scaleLevel(level)
+	switch {
+	case nb < nbInfo:
+		return severityDebug
+	case nb == nbInfo:
+		return severityInfo
+	case nb < nbWarning:
+		return severityNotice
+	}
+	// Each doubling beyond warning is one step more severe.
+	steps := int(math.Log2(nb / nbWarning))
+	return max(severityWarning-steps, severityEmergency)
+}
@@ -0,0 +1,118 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+// listenUnixgram creates a datagram socket standing in for the local syslog daemon. Socket paths have a short length
+// limit, so it avoids the long names of [testing.T.TempDir].
+func listenUnixgram(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "nblog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	// Fail rather than hang if a message never arrives.
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn, path
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslogRFC5424(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	conn, path := listenUnixgram(t)
+	w, err := nblog.NewSyslogWriter("unixgram", path, nblog.Tag("bpbrm"), nblog.Facility(nblog.FacilityDaemon))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+	logger := slog.New(nblog.New(w, nblog.Level(slog.LevelDebug)))
+
+	logger.Debug("debug")
+	logger.Info("info", slog.Int("a", 1))
+	logger.Warn("warn")
+	logger.Error("error")
+
+	pid := strconv.Itoa(os.Getpid())
+	prefix := `^<%d>1 \S+ \S+ bpbrm ` + pid + ` - - \S+ \S+ \[` + pid + `\] `
+	g.Expect(readDatagram(t, conn)).To(MatchRegexp(prefix+`<DEBUG> TestSyslogRFC5424: debug$`, 3*8+7))
+	g.Expect(readDatagram(t, conn)).To(MatchRegexp(prefix+`<INFO> TestSyslogRFC5424: info \{"a": 1\}$`, 3*8+6))
+	g.Expect(readDatagram(t, conn)).To(MatchRegexp(prefix+`<WARN> TestSyslogRFC5424: warn$`, 3*8+4))
+	g.Expect(readDatagram(t, conn)).To(MatchRegexp(prefix+`<ERROR> TestSyslogRFC5424: error$`, 3*8+3))
+}
+
+func TestSyslogRFC3164(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	conn, path := listenUnixgram(t)
+	w, err := nblog.NewSyslogWriter("unixgram", path, nblog.Tag("bptm"), nblog.Framing(nblog.RFC3164))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+	logger := slog.New(nblog.New(w))
+
+	logger.Log(t.Context(), slog.LevelError+4, "critical")
+
+	pid := strconv.Itoa(os.Getpid())
+	g.Expect(readDatagram(t, conn)).To(MatchRegexp(
+		`^<10>\w{3} [ \d]\d \d\d:\d\d:\d\d bptm\[%[1]s\]: \S+ \S+ \[%[1]s\] <ERROR\+4> TestSyslogRFC3164: critical$`, pid))
+}
+
+func TestSyslogTCP(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		length, _ := r.ReadString(' ')
+		n, _ := strconv.Atoi(length[:len(length)-1])
+		buf := make([]byte, n)
+		_, _ = io.ReadFull(r, buf)
+		received <- string(buf)
+	}()
+
+	w, err := nblog.NewSyslogWriter("tcp", listener.Addr().String())
+	g.Expect(err).NotTo(HaveOccurred())
+	defer w.Close()
+	slog.New(nblog.New(w)).Info("over tcp")
+
+	g.Expect(<-received).To(MatchRegexp(`^<14>1 .*<INFO> TestSyslogTCP: over tcp$`))
+}
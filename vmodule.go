@@ -0,0 +1,182 @@
+package nblog
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// closureSuffix matches the part of a function name that identifies a closure or a wrapper the compiler generated
+// within a named function, as in "pkg.F.func1" or "pkg.F.func1.2".
+var closureSuffix = regexp.MustCompile(`(\.(func|deferwrap|gowrap)\d+(\.\d+)*)+$`)
+
+// vmodule holds the per-function levels set with [VModule] or [SetVModule].
+type vmodule struct {
+	spec   string
+	rules  []vmoduleRule
+	lowest slog.Level
+	cache  sync.Map // maps a program counter to its vmoduleVerdict
+}
+
+// vmoduleRule is one "pattern=level" entry of a specification.
+type vmoduleRule struct {
+	pattern *regexp.Regexp
+	level   slog.Level
+}
+
+// vmoduleVerdict is the result of matching a caller against the rules.
+type vmoduleVerdict struct {
+	level   slog.Level
+	matched bool
+}
+
+// VModule sets levels for individual packages and functions, overriding the handler's level for records logged from
+// them. The specification is a comma-separated list of pattern=level entries, such as
+//
+//	catalog/*=debug,media.Mount=trace
+//
+// Each pattern is matched against the fully qualified name of the function that logged the record, as
+// [UseFullCallerName] would show it, or against any part of that name that follows a slash. In a pattern, "*" matches
+// any sequence of characters. A pattern ending in "/*" also matches the package named before the slash, so
+// "catalog/*" covers package catalog and every package beneath it. Methods are named like "media.(*Drive).Mount", and
+// closures are treated as part of the function that contains them. The first matching entry decides the level; for
+// records from other functions, the handler's own level applies.
+//
+// A level is a [slog.Level] name such as "debug" or "info+2", a NetBackup severity number as described for
+// [NumericSeverity], or "trace", which admits every record, including those from [Trace] and the most verbose levels
+// of [VerbosityLevel]. Because the decision depends on the caller, records logged without a program counter are
+// subject only to the handler's level.
+//
+// VModule panics if the specification is invalid. To change the levels while the program runs, use [SetVModule] or
+// [LevelHandler].
+func VModule(spec string) Option {
+	return func(h slog.Handler) {
+		vm, err := parseVModule(spec)
+		if err != nil {
+			panic(err)
+		}
+		base(h).vmodule.Store(vm)
+	}
+}
+
+// SetVModule replaces the per-function levels of h, as set with [VModule]. An empty specification removes them. The
+// handler must have been created by [New] or derived from such a handler.
+func SetVModule(h slog.Handler, spec string) error {
+	b, ok := rootHandler(h)
+	if !ok {
+		panic("SetVModule applied to wrong type")
+	}
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	b.vmodule.Store(vm)
+	return nil
+}
+
+// parseVModule compiles a specification. It returns nil for an empty specification.
+func parseVModule(spec string) (*vmodule, error) {
+	vm := &vmodule{spec: spec, lowest: slog.Level(math.MaxInt)}
+	for entry := range strings.SplitSeq(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseVModuleRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		vm.rules = append(vm.rules, rule)
+		vm.lowest = min(vm.lowest, rule.level)
+	}
+	if len(vm.rules) == 0 {
+		return nil, nil
+	}
+	return vm, nil
+}
+
+func parseVModuleRule(entry string) (vmoduleRule, error) {
+	pattern, setting, found := strings.Cut(entry, "=")
+	pattern, setting = strings.TrimSpace(pattern), strings.TrimSpace(setting)
+	if !found || pattern == "" {
+		return vmoduleRule{}, fmt.Errorf("invalid vmodule entry %q: want pattern=level", entry)
+	}
+	level := slog.Level(math.MinInt)
+	if !strings.EqualFold(setting, "trace") {
+		var err error
+		if level, err = parseLevelSetting(setting); err != nil {
+			return vmoduleRule{}, fmt.Errorf("invalid vmodule entry %q: %w", entry, err)
+		}
+	}
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		expr += `|` + regexp.QuoteMeta(prefix) + `\..*`
+	}
+	return vmoduleRule{pattern: regexp.MustCompile(`^(?:` + expr + `)$`), level: level}, nil
+}
+
+// levelFor returns the level that applies to records logged at pc, and whether any rule matched. The verdict for each
+// program counter is computed once.
+func (vm *vmodule) levelFor(pc uintptr) (slog.Level, bool) {
+	if cached, ok := vm.cache.Load(pc); ok {
+		verdict, _ := cached.(vmoduleVerdict)
+		return verdict.level, verdict.matched
+	}
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+	verdict := vm.match(closureSuffix.ReplaceAllString(frame.Function, ""))
+	vm.cache.Store(pc, verdict)
+	return verdict.level, verdict.matched
+}
+
+func (vm *vmodule) match(function string) vmoduleVerdict {
+	for _, rule := range vm.rules {
+		for name := function; ; {
+			if rule.pattern.MatchString(name) {
+				return vmoduleVerdict{level: rule.level, matched: true}
+			}
+			slash := strings.Index(name, "/")
+			if slash < 0 {
+				break
+			}
+			name = name[slash+1:]
+		}
+	}
+	return vmoduleVerdict{}
+}
+
+// vmoduleAdmits reports whether the per-function levels could admit a record at the given level from some caller, so
+// [baseHandler.Enabled] can't reject it before the caller is known.
+func (h *baseHandler) vmoduleAdmits(level slog.Level) bool {
+	vm := h.vmodule.Load()
+	return vm != nil && level >= vm.lowest
+}
+
+// admits makes the final decision about a record once its caller is known, applying the per-function levels. Records
+// are otherwise filtered by Enabled, so unless per-function levels or the flight recorder let Enabled admit records
+// below the handler's level, everything is admitted.
+func (h *baseHandler) admits(record slog.Record) bool {
+	vm := h.vmodule.Load()
+	if vm == nil && h.recorder == nil {
+		return true
+	}
+	level := h.minimumLevel()
+	if vm != nil && record.PC != 0 {
+		if l, ok := vm.levelFor(record.PC); ok {
+			level = l
+		}
+	}
+	return record.Level >= level
+}
+
+// vmoduleSpec returns the current specification, or an empty string if there is none.
+func (h *baseHandler) vmoduleSpec() string {
+	if vm := h.vmodule.Load(); vm != nil {
+		return vm.spec
+	}
+	return ""
+}
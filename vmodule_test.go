@@ -0,0 +1,112 @@
+package nblog_test
+
+//revive:disable:add-constant
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sweetkennedy.net/nblog"
+)
+
+func chatty(logger *slog.Logger) {
+	logger.Debug("chatty debug")
+	func() {
+		logger.Debug("chatty closure")
+	}()
+}
+
+func quiet(logger *slog.Logger) {
+	logger.Warn("quiet warning")
+	logger.Error("quiet error")
+}
+
+func traced(logger *slog.Logger) {
+	defer nblog.Trace(logger).Stop()
+	logger.Log(context.Background(), slog.LevelDebug-4, "traced detail")
+}
+
+func TestVModule(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	h := nblog.New(output, nblog.VModule("nblog_test.chatty=debug, nblog_test.quiet=error,nblog_test.traced=trace"))
+	logger := slog.New(h)
+
+	chatty(logger)
+	quiet(logger)
+	traced(logger)
+	logger.Debug("test debug")
+	logger.Info("test info")
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		HaveSuffix("<DEBUG> chatty: chatty debug"),
+		HaveSuffix("<DEBUG> func1: chatty closure"),
+		HaveSuffix("<ERROR> quiet: quiet error"),
+		HaveSuffix("<DEBUG> traced: Entered."),
+		HaveSuffix("<DEBUG-4> traced: traced detail"),
+		MatchRegexp(`<DEBUG> traced: Exited\. \{"duration": ".*"\}$`),
+		HaveSuffix("<INFO> TestVModule: test info"),
+	))
+}
+
+func TestSetVModule(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	h := nblog.New(output)
+	logger := slog.New(h).WithGroup("g")
+
+	chatty(logger)
+	g.Expect(nblog.SetVModule(logger.Handler(), "nblog_test.ch*=debug")).To(Succeed())
+	chatty(logger)
+	g.Expect(nblog.SetVModule(logger.Handler(), "")).To(Succeed())
+	chatty(logger)
+
+	g.Expect(nblog.SetVModule(h, "chatty")).To(MatchError(ContainSubstring("want pattern=level")))
+	g.Expect(nblog.SetVModule(h, "chatty=loud")).To(HaveOccurred())
+	g.Expect(func() { nblog.New(output, nblog.VModule("=debug")) }).To(Panic())
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		HaveSuffix("chatty: chatty debug"),
+		HaveSuffix("func1: chatty closure"),
+	))
+}
+
+func TestLevelHandlerVModule(t *testing.T) {
+	t.Parallel()
+	g := NewWithT(t)
+
+	output := &SyncLineBuffer{}
+	h := nblog.New(output, nblog.VModule("nblog_test.quiet=error"))
+	logger := slog.New(h)
+	server := nblog.LevelHandler(h)
+
+	g.Expect(getLevels(g, server)).To(HaveKeyWithValue("vmodule", "nblog_test.quiet=error"))
+	rec := putLevel(server, url.Values{"vmodule": {"nblog_test.chatty=debug"}})
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(getLevels(g, server)).To(Equal(map[string]string{
+		"level": "INFO", "configured": "INFO", "vmodule": "nblog_test.chatty=debug",
+	}))
+	chatty(logger)
+
+	rec = putLevel(server, url.Values{"vmodule": {"nblog_test.chatty=bogus"}})
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	rec = putLevel(server, url.Values{"vmodule": {""}})
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(getLevels(g, server)).ToNot(HaveKey("vmodule"))
+	chatty(logger)
+
+	g.Expect(output.Lines()).To(HaveExactElements(
+		MatchRegexp(`<INFO> log vmodule changed \{"from": "nblog_test.quiet=error", "to": "nblog_test.chatty=debug", `+
+			`"remote": ".*"\}$`),
+		HaveSuffix("chatty: chatty debug"),
+		HaveSuffix("func1: chatty closure"),
+		MatchRegexp(`<INFO> log vmodule changed \{"from": "nblog_test.chatty=debug", "to": "", "remote": ".*"\}$`),
+	))
+}